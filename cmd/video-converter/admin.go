@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+)
+
+// adminAPI exposes operational endpoints guarded by the admin token
+type adminAPI struct {
+	config       *config.Config
+	worker       *worker.Worker
+	requestDrain func()
+}
+
+// registerAdminRoutes registers the /v1/admin endpoints on the given mux.
+// requestDrain starts the same drain-and-exit sequence as SIGTERM.
+func registerAdminRoutes(mux *http.ServeMux, cfg *config.Config, w *worker.Worker, requestDrain func()) {
+	api := &adminAPI{
+		config:       cfg,
+		worker:       w,
+		requestDrain: requestDrain,
+	}
+
+	mux.HandleFunc("GET /v1/admin/drain", api.authorized(api.handleDrainStatus))
+	mux.HandleFunc("POST /v1/admin/drain", api.authorized(api.handleDrain))
+}
+
+// authorized rejects requests without the admin bearer token. The
+// endpoints are disabled unless a token is configured.
+func (a *adminAPI) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return requireToken(a.config.Server.AdminToken, "admin", next)
+}
+
+// handleDrain starts draining the service; it exits once running jobs finish
+func (a *adminAPI) handleDrain(w http.ResponseWriter, r *http.Request) {
+	a.requestDrain()
+
+	// The drain starts asynchronously; report it as under way
+	status := a.drainStatus()
+	status["draining"] = true
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// handleDrainStatus reports drain progress
+func (a *adminAPI) handleDrainStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.drainStatus())
+}
+
+// drainStatus summarises the worker's drain state
+func (a *adminAPI) drainStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"draining":    a.worker.Draining(),
+		"runningJobs": a.worker.RunningJobs(),
+		"queuedJobs":  a.worker.QueueLength(),
+	}
+}
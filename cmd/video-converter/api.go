@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// jobAPI exposes the job submission and status REST API
+type jobAPI struct {
+	config *config.Config
+	worker *worker.Worker
+}
+
+// registerJobRoutes registers the /v1/jobs and /v1/dead-letters endpoints
+// on the given mux
+func registerJobRoutes(mux *http.ServeMux, cfg *config.Config, w *worker.Worker) {
+	api := &jobAPI{
+		config: cfg,
+		worker: w,
+	}
+
+	mux.HandleFunc("POST /v1/jobs", api.authorized(api.handleSubmitJob))
+	mux.HandleFunc("GET /v1/jobs", api.authorized(api.handleListJobs))
+	mux.HandleFunc("GET /v1/jobs/{id}", api.authorized(api.handleGetJob))
+	mux.HandleFunc("DELETE /v1/jobs/{id}", api.authorized(api.handleCancelJob))
+
+	mux.HandleFunc("GET /v1/dead-letters", api.authorized(api.handleListDeadLetters))
+	mux.HandleFunc("GET /v1/dead-letters/{id}", api.authorized(api.handleGetDeadLetter))
+	mux.HandleFunc("DELETE /v1/dead-letters/{id}", api.authorized(api.handleDeleteDeadLetter))
+	mux.HandleFunc("POST /v1/dead-letters/{id}/replay", api.authorized(api.handleReplayDeadLetter))
+}
+
+// authorized rejects requests without the API bearer token. The endpoints
+// submit, cancel and replay jobs, so they are disabled unless a token is
+// configured.
+func (a *jobAPI) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return requireToken(a.config.Server.APIToken, "API", next)
+}
+
+// handleSubmitJob accepts a conversion job and queues it for processing.
+// A request repeating an Idempotency-Key header, or a source URI and
+// checksum already submitted, gets the job created by the first request,
+// with 200 instead of 202.
+func (a *jobAPI) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var job models.ConversionJob
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid job payload: %v", err))
+		return
+	}
+
+	if err := a.worker.ValidateJob(&job); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var keys []string
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		keys = append(keys, worker.IdempotencyDedupKey(key))
+	}
+	if job.Source.Checksum != "" {
+		keys = append(keys, worker.SourceDedupKey(job.Source.URI, job.Source.Checksum))
+	}
+
+	submitted, duplicate, err := a.worker.SubmitJobOnce(&job, keys...)
+	if err != nil {
+		switch {
+		case errors.Is(err, worker.ErrJobExists):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, worker.ErrQueueFull):
+			writeQueueFull(w, err)
+		case errors.Is(err, worker.ErrDraining):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/v1/jobs/"+submitted.JobID)
+	if duplicate {
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeJSON(w, http.StatusOK, submitted)
+		return
+	}
+
+	slog.Info("Submitted conversion job from API",
+		"jobId", job.JobID,
+		"videoId", job.VideoID,
+		"template", job.Template,
+		"sourceUri", job.Source.URI,
+	)
+
+	writeJSON(w, http.StatusAccepted, submitted)
+}
+
+// handleListJobs lists known jobs, optionally filtered by state
+func (a *jobAPI) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	// Accept both ?state=a&state=b and ?state=a,b
+	var states []models.JobState
+	for _, value := range r.URL.Query()["state"] {
+		for _, state := range strings.Split(value, ",") {
+			state = strings.TrimSpace(state)
+			if state == "" {
+				continue
+			}
+			if !isValidJobState(models.JobState(state)) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid job state: %s", state))
+				return
+			}
+			states = append(states, models.JobState(state))
+		}
+	}
+
+	jobs := a.worker.ListJobs(states...)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// handleGetJob returns a single job with its live status
+func (a *jobAPI) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.worker.GetJob(r.PathValue("id"))
+	if err != nil {
+		writeWorkerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleCancelJob cancels a job. A running job is still being stopped when
+// the response is sent, which is reported as 202 Accepted.
+func (a *jobAPI) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.worker.CancelJob(r.PathValue("id"))
+	if err != nil {
+		writeWorkerError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if job.Status.State != models.JobStateCancelled {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, job)
+}
+
+// replayRequest is the optional body of a dead-letter replay request
+type replayRequest struct {
+	Template string `json:"template,omitempty"` // Template to use instead of the original
+}
+
+// handleListDeadLetters lists permanently failed jobs, newest first
+func (a *jobAPI) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := a.worker.ListDeadLetters(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deadLetters": letters,
+		"count":       len(letters),
+	})
+}
+
+// handleGetDeadLetter returns a single dead letter
+func (a *jobAPI) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := a.worker.GetDeadLetter(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeWorkerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, letter)
+}
+
+// handleDeleteDeadLetter discards a dead letter without replaying it
+func (a *jobAPI) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := a.worker.DeleteDeadLetter(r.Context(), r.PathValue("id")); err != nil {
+		writeWorkerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReplayDeadLetter re-submits a failed job as a new job
+func (a *jobAPI) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid replay payload: %v", err))
+			return
+		}
+	}
+
+	job, err := a.worker.ReplayDeadLetter(r.Context(), r.PathValue("id"), req.Template)
+	if err != nil {
+		writeWorkerError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/jobs/"+job.JobID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// isValidJobState reports whether state is a known job state
+func isValidJobState(state models.JobState) bool {
+	switch state {
+	case models.JobStatePending, models.JobStateProcessing, models.JobStateCompleted,
+		models.JobStateFailed, models.JobStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeWorkerError maps worker errors to HTTP status codes
+func writeWorkerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, worker.ErrJobNotFound), errors.Is(err, worker.ErrDeadLetterNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, worker.ErrJobNotCancellable), errors.Is(err, worker.ErrJobExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, worker.ErrInvalidJob):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, worker.ErrQueueFull):
+		writeQueueFull(w, err)
+	case errors.Is(err, worker.ErrDraining):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeQueueFull asks the client to back off and resubmit later
+func writeQueueFull(w http.ResponseWriter, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(worker.QueueFullRetryAfter.Seconds())))
+	writeError(w, http.StatusTooManyRequests, err.Error())
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode JSON response", "error", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// newTestAPI starts a worker running one job at a time with room for one
+// queued job, and serves the job API for it
+func newTestAPI(t *testing.T, ctx context.Context, apiToken string) *httptest.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Server: config.ServerConfig{APIToken: apiToken},
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     1,
+			JobTimeoutMinutes: 1,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage: config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		// The binary is only run with -version at startup
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	}
+
+	w, err := worker.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+	go w.Start(ctx)
+
+	mux := http.NewServeMux()
+	registerJobRoutes(mux, cfg, w)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// apiCall sends an authenticated request and decodes the JSON response
+func apiCall(t *testing.T, method, url string, body interface{}, header http.Header, out interface{}) *http.Response {
+	t.Helper()
+
+	var payload []byte
+	switch body := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(body)
+	default:
+		payload, _ = json.Marshal(body)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp
+}
+
+func TestJobAPI(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The source server holds downloads open so the first job keeps running
+	release := make(chan struct{})
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer source.Close()
+	defer close(release)
+
+	api := newTestAPI(t, ctx, "secret").URL
+	newJob := func(videoID string) models.ConversionJob {
+		return models.ConversionJob{
+			VideoID:  videoID,
+			Template: "default",
+			Source:   models.SourceConfig{URI: source.URL + "/" + videoID + ".mp4", Type: "http"},
+		}
+	}
+
+	var running models.ConversionJob
+	resp := apiCall(t, http.MethodPost, api+"/v1/jobs", newJob("video-1"), nil, &running)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/v1/jobs/"+running.JobID {
+		t.Fatalf("Expected 202 with a Location, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for running.Status.State != models.JobStateProcessing {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected job %s to start, still %s", running.JobID, running.Status.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		apiCall(t, http.MethodGet, api+"/v1/jobs/"+running.JobID, nil, nil, &running)
+	}
+
+	// A repeated idempotency key returns the first job
+	var queued, replayed models.ConversionJob
+	key := http.Header{"Idempotency-Key": {"upload-2"}}
+	if resp := apiCall(t, http.MethodPost, api+"/v1/jobs", newJob("video-2"), key, &queued); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+	resp = apiCall(t, http.MethodPost, api+"/v1/jobs", newJob("video-2"), key, &replayed)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Idempotent-Replayed") != "true" || replayed.JobID != queued.JobID {
+		t.Errorf("Expected replay of %s with 200, got %d %s", queued.JobID, resp.StatusCode, replayed.JobID)
+	}
+
+	// Error mapping
+	for _, test := range []struct {
+		method, path string
+		body         interface{}
+		status       int
+	}{
+		{http.MethodPost, "/v1/jobs", "{not json", http.StatusBadRequest},
+		{http.MethodPost, "/v1/jobs", models.ConversionJob{VideoID: "video-3"}, http.StatusBadRequest},
+		{http.MethodPost, "/v1/jobs", newJob("video-3"), http.StatusTooManyRequests},
+		{http.MethodGet, "/v1/jobs?state=bogus", nil, http.StatusBadRequest},
+		{http.MethodGet, "/v1/jobs/missing", nil, http.StatusNotFound},
+		{http.MethodDelete, "/v1/jobs/missing", nil, http.StatusNotFound},
+		{http.MethodGet, "/v1/dead-letters/missing", nil, http.StatusNotFound},
+	} {
+		var body map[string]string
+		resp := apiCall(t, test.method, api+test.path, test.body, nil, &body)
+		if resp.StatusCode != test.status || body["error"] == "" {
+			t.Errorf("%s %s: expected %d with an error, got %d %v", test.method, test.path, test.status, resp.StatusCode, body)
+		}
+		if test.status == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+			t.Error("Expected Retry-After on a full queue")
+		}
+	}
+
+	var list struct {
+		Jobs  []models.ConversionJob `json:"jobs"`
+		Count int                    `json:"count"`
+	}
+	apiCall(t, http.MethodGet, api+"/v1/jobs?state=pending", nil, nil, &list)
+	if list.Count != 1 || list.Jobs[0].JobID != queued.JobID {
+		t.Errorf("Expected only %s pending, got %+v", queued.JobID, list)
+	}
+	apiCall(t, http.MethodGet, api+"/v1/jobs?state=pending,processing", nil, nil, &list)
+	if list.Count != 2 {
+		t.Errorf("Expected 2 pending or processing jobs, got %d", list.Count)
+	}
+
+	// A queued job is cancelled at once, a running one is still stopping
+	var cancelled models.ConversionJob
+	resp = apiCall(t, http.MethodDelete, api+"/v1/jobs/"+queued.JobID, nil, nil, &cancelled)
+	if resp.StatusCode != http.StatusOK || cancelled.Status.State != models.JobStateCancelled {
+		t.Errorf("Expected 200 and cancelled, got %d %s", resp.StatusCode, cancelled.Status.State)
+	}
+	if resp := apiCall(t, http.MethodDelete, api+"/v1/jobs/"+queued.JobID, nil, nil, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling a cancelled job, got %d", resp.StatusCode)
+	}
+	resp = apiCall(t, http.MethodDelete, api+"/v1/jobs/"+running.JobID, nil, nil, &cancelled)
+	if resp.StatusCode != http.StatusAccepted || cancelled.Status.State != models.JobStateProcessing {
+		t.Errorf("Expected 202 and still processing, got %d %s", resp.StatusCode, cancelled.Status.State)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for cancelled.Status.State != models.JobStateCancelled {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected job %s to be cancelled, still %s", running.JobID, cancelled.Status.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		apiCall(t, http.MethodGet, api+"/v1/jobs/"+running.JobID, nil, nil, &cancelled)
+	}
+}
+
+func TestJobAPI_RequiresToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api := newTestAPI(t, ctx, "secret").URL
+	for _, header := range []http.Header{{"Authorization": {"Bearer wrong"}}, {"Authorization": {"secret"}}} {
+		for _, path := range []string{"/v1/jobs", "/v1/dead-letters"} {
+			if resp := apiCall(t, http.MethodGet, api+path, nil, header, nil); resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %s with %v, got %d", path, header, resp.StatusCode)
+			}
+		}
+	}
+
+	// Without a configured token the endpoints are disabled
+	api = newTestAPI(t, ctx, "").URL
+	if resp := apiCall(t, http.MethodGet, api+"/v1/jobs", nil, nil, nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a configured token, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// requireToken rejects requests without the given bearer token. The
+// endpoints, named by scope in error messages, are disabled unless a token
+// is configured.
+func requireToken(token, scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("%s endpoints are not configured", scope))
+			return
+		}
+
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Sprintf("invalid or missing %s token", scope))
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+const deadLetterUsage = `Usage: video-converter dead-letter [-server URL] [-token TOKEN] <command> [arguments]
+
+Commands:
+  list                          List permanently failed jobs, newest first
+  show <job-id>                 Print a dead letter as JSON
+  replay [-template T] <job-id> Re-submit a failed job, optionally with another template
+  delete <job-id>               Discard a dead letter without replaying it
+`
+
+// runDeadLetterCommand implements the dead-letter subcommand against a
+// running service's HTTP API and returns the process exit code
+func runDeadLetterCommand(args []string) int {
+	flags := flag.NewFlagSet("dead-letter", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), deadLetterUsage) }
+	server := flags.String("server", "http://localhost:8080", "Base URL of the video converter service")
+	token := flags.String("token", os.Getenv("SERVER_API_TOKEN"), "API bearer token (default $SERVER_API_TOKEN)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	client := &deadLetterClient{
+		baseURL:    *server,
+		token:      *token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	var err error
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "list":
+		err = client.list()
+	case "show":
+		err = withJobID(rest, client.show)
+	case "delete":
+		err = withJobID(rest, client.delete)
+	case "replay":
+		replayFlags := flag.NewFlagSet("replay", flag.ContinueOnError)
+		template := replayFlags.String("template", "", "Template to use instead of the original")
+		if err := replayFlags.Parse(rest); err != nil {
+			return 2
+		}
+		err = withJobID(replayFlags.Args(), func(jobID string) error {
+			return client.replay(jobID, *template)
+		})
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dead-letter %s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// withJobID calls fn with the single job ID argument
+func withJobID(args []string, fn func(jobID string) error) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one job ID")
+	}
+	return fn(args[0])
+}
+
+// deadLetterClient calls the service's dead-letter endpoints
+type deadLetterClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// list prints a table of dead letters
+func (c *deadLetterClient) list() error {
+	var result struct {
+		DeadLetters []*models.DeadLetter `json:"deadLetters"`
+	}
+	if err := c.do(http.MethodGet, "/v1/dead-letters", nil, &result); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "JOB ID\tVIDEO ID\tTEMPLATE\tATTEMPTS\tFAILED AT\tERROR")
+	for _, letter := range result.DeadLetters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			letter.Job.JobID,
+			letter.Job.VideoID,
+			letter.Job.Template,
+			letter.Job.Attempts,
+			letter.FailedAt.Format(time.RFC3339),
+			letter.Error,
+		)
+	}
+	return tw.Flush()
+}
+
+// show prints a single dead letter
+func (c *deadLetterClient) show(jobID string) error {
+	var letter json.RawMessage
+	if err := c.do(http.MethodGet, "/v1/dead-letters/"+url.PathEscape(jobID), nil, &letter); err != nil {
+		return err
+	}
+	return printJSON(letter)
+}
+
+// replay re-submits a dead letter and prints the new job
+func (c *deadLetterClient) replay(jobID, template string) error {
+	body, err := json.Marshal(replayRequest{Template: template})
+	if err != nil {
+		return err
+	}
+
+	var job models.ConversionJob
+	if err := c.do(http.MethodPost, "/v1/dead-letters/"+url.PathEscape(jobID)+"/replay", body, &job); err != nil {
+		return err
+	}
+
+	fmt.Printf("Replayed %s as job %s (template %s)\n", jobID, job.JobID, job.Template)
+	return nil
+}
+
+// delete discards a dead letter
+func (c *deadLetterClient) delete(jobID string) error {
+	if err := c.do(http.MethodDelete, "/v1/dead-letters/"+url.PathEscape(jobID), nil, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted dead letter %s\n", jobID)
+	return nil
+}
+
+// do sends a request and decodes a JSON response into out, if given
+func (c *deadLetterClient) do(method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// printJSON writes raw JSON to stdout indented
+func printJSON(data []byte) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(os.Stdout)
+	return err
+}
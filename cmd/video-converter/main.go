@@ -29,6 +29,11 @@ const (
 )
 
 func main() {
+	// Subcommands take their own flags
+	if len(os.Args) > 1 && os.Args[1] == "dead-letter" {
+		os.Exit(runDeadLetterCommand(os.Args[2:]))
+	}
+
 	// Parse command-line flags
 	var (
 		testMode    = flag.Bool("test", false, "Run in test mode")
@@ -135,10 +140,17 @@ func runProductionMode() {
 
 	eventRouter := events.NewRouter(cfg, w)
 
+	// The admin drain endpoint triggers the same shutdown as SIGTERM
+	drainRequested := make(chan struct{})
+	var drainOnce sync.Once
+	requestDrain := func() {
+		drainOnce.Do(func() { close(drainRequested) })
+	}
+
 	// Start HTTP server for health checks
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler: setupHTTPRoutes(cfg),
+		Handler: setupHTTPRoutes(ctx, cfg, w, requestDrain),
 	}
 
 	// Start health check server
@@ -185,10 +197,28 @@ func runProductionMode() {
 		w.Start(ctx)
 	}()
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal or a drain request
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	select {
+	case sig := <-sigChan:
+		slog.Info("Received shutdown signal, draining", "signal", sig.String())
+	case <-drainRequested:
+		slog.Info("Drain requested via admin endpoint")
+	}
+
+	// Drain: readiness goes false, new jobs are refused and running jobs get
+	// until the drain deadline to finish. A second signal skips the wait.
+	drainTimeout := time.Duration(cfg.Processing.DrainTimeoutMinutes) * time.Minute
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+	drained := make(chan error, 1)
+	go func() { drained <- w.Drain(drainCtx) }()
+	select {
+	case <-drained:
+	case sig := <-sigChan:
+		slog.Warn("Received second signal, interrupting running jobs", "signal", sig.String())
+	}
+	drainCancel()
 
 	slog.Info("Shutting down service...")
 
@@ -236,19 +266,23 @@ func setLogLevel(level string) {
 }
 
 // setupHTTPRoutes creates the main HTTP server routes
-func setupHTTPRoutes(cfg *config.Config) http.Handler {
+func setupHTTPRoutes(ctx context.Context, cfg *config.Config, wk *worker.Worker, requestDrain func()) http.Handler {
 	mux := http.NewServeMux()
 
-	// WebSocket endpoint for events (if enabled)
-	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement WebSocket handler
-		http.Error(w, "WebSocket handler not implemented", http.StatusNotImplemented)
-	})
+	// Job submission and status API
+	registerJobRoutes(mux, cfg, wk)
+
+	// Operational endpoints
+	registerAdminRoutes(mux, cfg, wk, requestDrain)
+
+	// WebSocket endpoint for job submission and live progress
+	mux.Handle("GET /events", events.NewWebSocketServer(ctx, cfg, wk))
 
 	// Status endpoint
 	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"service":"%s","version":"%s","status":"running"}`, serviceName, serviceVersion)
+		fmt.Fprintf(w, `{"service":"%s","version":"%s","status":"running","queuedJobs":%d}`,
+			serviceName, serviceVersion, wk.QueueLength())
 	})
 
 	return mux
@@ -266,7 +300,12 @@ func setupHealthRoutes(w *worker.Worker) http.Handler {
 
 	// Readiness probe
 	mux.HandleFunc("/ready", func(rw http.ResponseWriter, r *http.Request) {
-		// Simple readiness check - service is ready if it's running
+		// Not ready while draining so no new work is routed here
+		if w.Draining() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(rw, "Draining")
+			return
+		}
 		rw.WriteHeader(http.StatusOK)
 		fmt.Fprint(rw, "Ready")
 	})
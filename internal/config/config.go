@@ -25,6 +25,9 @@ type ServerConfig struct {
 	Port            int    `yaml:"port" json:"port"`
 	Host            string `yaml:"host" json:"host"`
 	HealthCheckPort int    `yaml:"health_check_port" json:"health_check_port"`
+	EventsToken     string `yaml:"events_token" json:"events_token"` // Bearer token for the /events WebSocket endpoint
+	AdminToken      string `yaml:"admin_token" json:"admin_token"`   // Bearer token for the /v1/admin endpoints
+	APIToken        string `yaml:"api_token" json:"api_token"`       // Bearer token for the /v1/jobs and /v1/dead-letters endpoints
 }
 
 type EventSourcesConfig struct {
@@ -65,16 +68,54 @@ type AzureBlobStorage struct {
 }
 
 type S3Storage struct {
-	Bucket string `yaml:"bucket" json:"bucket"`
-	Region string `yaml:"region" json:"region"`
+	Bucket               string `yaml:"bucket" json:"bucket"`
+	Region               string `yaml:"region" json:"region"`
+	Endpoint             string `yaml:"endpoint" json:"endpoint"`             // Custom endpoint for S3-compatible stores (MinIO)
+	UsePathStyle         bool   `yaml:"use_path_style" json:"use_path_style"` // Required by most S3-compatible stores
+	AccessKeyID          string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey      string `yaml:"secret_access_key" json:"secret_access_key"`
+	SessionToken         string `yaml:"session_token" json:"session_token"`
+	PresignExpiryMinutes int    `yaml:"presign_expiry_minutes" json:"presign_expiry_minutes"`
 }
 
 type ProcessingConfig struct {
-	MaxConcurrentJobs int    `yaml:"max_concurrent_jobs" json:"max_concurrent_jobs"`
-	JobTimeoutMinutes int    `yaml:"job_timeout_minutes" json:"job_timeout_minutes"`
-	TempDir           string `yaml:"temp_dir" json:"temp_dir"`
-	OutputsDir        string `yaml:"outputs_dir" json:"outputs_dir"` // Local filesystem staging area
-	MaxTempDiskGB     int    `yaml:"max_temp_disk_gb" json:"max_temp_disk_gb"`
+	MaxConcurrentJobs   int              `yaml:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+	MaxQueuedJobs       int              `yaml:"max_queued_jobs" json:"max_queued_jobs"`           // Backlog size; further submissions are refused with 429
+	MaxParallelEncodes  int              `yaml:"max_parallel_encodes" json:"max_parallel_encodes"` // Renditions and outputs of one job encoded at once
+	EncodeSlots         int              `yaml:"encode_slots" json:"encode_slots"`                 // Encodes running at once across all jobs (default: max_concurrent_jobs)
+	TenantMetadataKey   string           `yaml:"tenant_metadata_key" json:"tenant_metadata_key"`   // Job metadata key that workers are shared fairly across
+	MaxClientPriority   int              `yaml:"max_client_priority" json:"max_client_priority"`   // Highest metadata priority a submitted job may request
+	JobTimeoutMinutes   int              `yaml:"job_timeout_minutes" json:"job_timeout_minutes"`
+	DrainTimeoutMinutes int              `yaml:"drain_timeout_minutes" json:"drain_timeout_minutes"` // How long shutdown waits for running jobs
+	TempDir             string           `yaml:"temp_dir" json:"temp_dir"`
+	OutputsDir          string           `yaml:"outputs_dir" json:"outputs_dir"` // Local filesystem staging area
+	MaxTempDiskGB       int              `yaml:"max_temp_disk_gb" json:"max_temp_disk_gb"`
+	JobRetentionMinutes int              `yaml:"job_retention_minutes" json:"job_retention_minutes"` // How long finished jobs stay queryable
+	MaxRetainedJobs     int              `yaml:"max_retained_jobs" json:"max_retained_jobs"`         // Upper bound on finished jobs kept in memory
+	JobStore            JobStoreConfig   `yaml:"job_store" json:"job_store"`
+	DeadLetter          DeadLetterConfig `yaml:"dead_letter" json:"dead_letter"`
+	Dedup               DedupConfig      `yaml:"dedup" json:"dedup"`
+}
+
+// JobStoreConfig configures durable persistence of queued and running jobs
+type JobStoreConfig struct {
+	Type string `yaml:"type" json:"type"` // Backend: bolt, memory
+	Path string `yaml:"path" json:"path"` // Database file for the bolt backend
+}
+
+// DedupConfig configures how long event IDs, source versions and
+// idempotency keys are remembered to suppress duplicate jobs
+type DedupConfig struct {
+	Type       string `yaml:"type" json:"type"`               // Backend: bolt, memory
+	Path       string `yaml:"path" json:"path"`               // Database file for the bolt backend
+	TTLMinutes int    `yaml:"ttl_minutes" json:"ttl_minutes"` // How long a key maps to its job
+}
+
+// DeadLetterConfig configures where permanently failed jobs are kept
+type DeadLetterConfig struct {
+	Type   string `yaml:"type" json:"type"`     // Backend: disk, storage (the configured output storage)
+	Path   string `yaml:"path" json:"path"`     // Directory for the disk backend
+	Prefix string `yaml:"prefix" json:"prefix"` // Key prefix for the storage backend
 }
 
 type FFmpegConfig struct {
@@ -98,6 +139,18 @@ type JobTemplate struct {
 	Outputs       []OutputConfig     `yaml:"outputs" json:"outputs"`
 	FFmpeg        JobFFmpegConfig    `yaml:"ffmpeg" json:"ffmpeg"`
 	Notifications NotificationConfig `yaml:"notifications" json:"notifications"`
+	Retry         RetryConfig        `yaml:"retry" json:"retry"`
+	Priority      int                `yaml:"priority" json:"priority"` // Default job priority; higher runs first
+	// Encodes of a job run at once; 0 uses processing.max_parallel_encodes
+	ParallelEncodes int `yaml:"parallel_encodes" json:"parallel_encodes"`
+}
+
+// RetryConfig controls how jobs failing with transient errors are retried.
+// Zero values use the defaults.
+type RetryConfig struct {
+	MaxAttempts           int `yaml:"max_attempts" json:"max_attempts"`                       // Total attempts including the first (default 3; 1 disables retries)
+	InitialBackoffSeconds int `yaml:"initial_backoff_seconds" json:"initial_backoff_seconds"` // Delay before the first retry (default 30), doubled for each further retry
+	MaxBackoffSeconds     int `yaml:"max_backoff_seconds" json:"max_backoff_seconds"`         // Upper bound for the delay (default 600)
 }
 
 type OutputConfig struct {
@@ -108,6 +161,14 @@ type OutputConfig struct {
 	SegmentLengthS int             `yaml:"segment_length_s" json:"segment_length_s"`
 	Container      string          `yaml:"container" json:"container"`
 	Destination    string          `yaml:"destination" json:"destination"`
+	Upscale        string          `yaml:"upscale" json:"upscale"`                 // Profiles above the source resolution: skip (default), cap or allow
+	Fit            string          `yaml:"fit" json:"fit"`                         // Default fit mode of the output's profiles
+	SinglePass     bool            `yaml:"single_pass" json:"single_pass"`         // Encode the whole HLS ladder with one ffmpeg process
+	HLSPlaylist    bool            `yaml:"hls_playlist" json:"hls_playlist"`       // DASH: also write HLS playlists over the same segments
+	Codec          string          `yaml:"codec" json:"codec"`                     // Default video codec of the output's profiles: h264 (default), hevc, vp9 or av1
+	AudioTracks    []string        `yaml:"audio_tracks" json:"audio_tracks"`       // Source audio tracks by language tag, or "all"; default the first track
+	AudioOnly      bool            `yaml:"audio_only" json:"audio_only"`           // HLS: add an audio-only rendition for low-bandwidth clients
+	SubtitleTracks []string        `yaml:"subtitle_tracks" json:"subtitle_tracks"` // HLS: subtitles by language tag, "all" (default) or "none"
 }
 
 type ProfileConfig struct {
@@ -116,6 +177,11 @@ type ProfileConfig struct {
 	Height           int    `yaml:"height" json:"height"`
 	VideoBitrateKbps int    `yaml:"video_bitrate_kbps" json:"video_bitrate_kbps"`
 	AudioBitrateKbps int    `yaml:"audio_bitrate_kbps" json:"audio_bitrate_kbps"`
+	Fit              string `yaml:"fit" json:"fit"`                           // How the source fits the box: source (default), width, contain or cover
+	Codec            string `yaml:"codec" json:"codec"`                       // Video codec, overriding the output's
+	RateControl      string `yaml:"rate_control" json:"rate_control"`         // bitrate (default), crf, capped_crf, two_pass or cbr
+	CRF              *int   `yaml:"crf" json:"crf"`                           // Quality of crf and capped_crf encodes; unset selects the codec default
+	MaxBitrateKbps   int    `yaml:"max_bitrate_kbps" json:"max_bitrate_kbps"` // Peak bitrate of capped_crf (default video_bitrate_kbps) and two_pass encodes
 }
 
 type JobFFmpegConfig struct {
@@ -125,9 +191,12 @@ type JobFFmpegConfig struct {
 }
 
 type NotificationConfig struct {
-	WebhookURL string `yaml:"webhook_url" json:"webhook_url"`
-	OnComplete bool   `yaml:"on_complete" json:"on_complete"`
-	OnFailure  bool   `yaml:"on_failure" json:"on_failure"`
+	WebhookURL     string `yaml:"webhook_url" json:"webhook_url"`
+	OnComplete     bool   `yaml:"on_complete" json:"on_complete"`
+	OnFailure      bool   `yaml:"on_failure" json:"on_failure"`
+	Secret         string `yaml:"secret" json:"secret"`                   // HMAC-SHA256 signing key; requests are unsigned if empty
+	MaxRetries     int    `yaml:"max_retries" json:"max_retries"`         // Retries after the first attempt (0 uses the default)
+	TimeoutSeconds int    `yaml:"timeout_seconds" json:"timeout_seconds"` // Per-attempt request timeout (0 uses the default)
 }
 
 // Load loads configuration from environment variables and config.yaml file
@@ -140,10 +209,30 @@ func Load() (*Config, error) {
 			HealthCheckPort: 8081,
 		},
 		Processing: ProcessingConfig{
-			MaxConcurrentJobs: 2,
-			JobTimeoutMinutes: 60, // Increased default for longer video processing
-			TempDir:           "./video_temp",
-			MaxTempDiskGB:     10,
+			MaxConcurrentJobs:   2,
+			MaxQueuedJobs:       1000,
+			MaxParallelEncodes:  1,
+			TenantMetadataKey:   "tenant",
+			JobTimeoutMinutes:   60, // Increased default for longer video processing
+			DrainTimeoutMinutes: 60,
+			TempDir:             "./video_temp",
+			MaxTempDiskGB:       10,
+			JobRetentionMinutes: 24 * 60,
+			MaxRetainedJobs:     1000,
+			JobStore: JobStoreConfig{
+				Type: "bolt",
+				Path: "./video_state/jobs.db",
+			},
+			DeadLetter: DeadLetterConfig{
+				Type:   "disk",
+				Path:   "./video_state/dead-letters",
+				Prefix: "dead-letters/",
+			},
+			Dedup: DedupConfig{
+				Type:       "bolt",
+				Path:       "./video_state/dedup.db",
+				TTLMinutes: 24 * 60,
+			},
 		},
 		FFmpeg: FFmpegConfig{
 			BinaryPath:    "ffmpeg",
@@ -171,6 +260,11 @@ func Load() (*Config, error) {
 	// Override with environment variables
 	loadFromEnv(cfg)
 
+	// Every running job can encode by default
+	if cfg.Processing.EncodeSlots == 0 {
+		cfg.Processing.EncodeSlots = cfg.Processing.MaxConcurrentJobs
+	}
+
 	// Validate configuration
 	if err := validate(cfg); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
@@ -195,6 +289,15 @@ func loadFromEnv(cfg *Config) {
 			cfg.Server.HealthCheckPort = port
 		}
 	}
+	if val := os.Getenv("SERVER_EVENTS_TOKEN"); val != "" {
+		cfg.Server.EventsToken = val
+	}
+	if val := os.Getenv("SERVER_ADMIN_TOKEN"); val != "" {
+		cfg.Server.AdminToken = val
+	}
+	if val := os.Getenv("SERVER_API_TOKEN"); val != "" {
+		cfg.Server.APIToken = val
+	}
 
 	// Event sources
 	if val := os.Getenv("EVENT_SOURCES_AZURE_EVENTGRID_ENDPOINT"); val != "" {
@@ -232,6 +335,26 @@ func loadFromEnv(cfg *Config) {
 	if val := os.Getenv("STORAGE_S3_REGION"); val != "" {
 		cfg.Storage.S3.Region = val
 	}
+	if val := os.Getenv("STORAGE_S3_ENDPOINT"); val != "" {
+		cfg.Storage.S3.Endpoint = val
+	}
+	if val := os.Getenv("STORAGE_S3_USE_PATH_STYLE"); val != "" {
+		cfg.Storage.S3.UsePathStyle = strings.ToLower(val) == "true"
+	}
+	if val := os.Getenv("STORAGE_S3_ACCESS_KEY_ID"); val != "" {
+		cfg.Storage.S3.AccessKeyID = val
+	}
+	if val := os.Getenv("STORAGE_S3_SECRET_ACCESS_KEY"); val != "" {
+		cfg.Storage.S3.SecretAccessKey = val
+	}
+	if val := os.Getenv("STORAGE_S3_SESSION_TOKEN"); val != "" {
+		cfg.Storage.S3.SessionToken = val
+	}
+	if val := os.Getenv("STORAGE_S3_PRESIGN_EXPIRY_MINUTES"); val != "" {
+		if minutes, err := strconv.Atoi(val); err == nil {
+			cfg.Storage.S3.PresignExpiryMinutes = minutes
+		}
+	}
 
 	// Processing config
 	if val := os.Getenv("PROCESSING_MAX_CONCURRENT_JOBS"); val != "" {
@@ -239,6 +362,29 @@ func loadFromEnv(cfg *Config) {
 			cfg.Processing.MaxConcurrentJobs = jobs
 		}
 	}
+	if val := os.Getenv("PROCESSING_MAX_QUEUED_JOBS"); val != "" {
+		if jobs, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.MaxQueuedJobs = jobs
+		}
+	}
+	if val := os.Getenv("PROCESSING_MAX_PARALLEL_ENCODES"); val != "" {
+		if encodes, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.MaxParallelEncodes = encodes
+		}
+	}
+	if val := os.Getenv("PROCESSING_ENCODE_SLOTS"); val != "" {
+		if slots, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.EncodeSlots = slots
+		}
+	}
+	if val := os.Getenv("PROCESSING_TENANT_METADATA_KEY"); val != "" {
+		cfg.Processing.TenantMetadataKey = val
+	}
+	if val := os.Getenv("PROCESSING_MAX_CLIENT_PRIORITY"); val != "" {
+		if priority, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.MaxClientPriority = priority
+		}
+	}
 	if val := os.Getenv("PROCESSING_OUTPUTS_DIR"); val != "" {
 		cfg.Processing.OutputsDir = val
 	}
@@ -247,6 +393,11 @@ func loadFromEnv(cfg *Config) {
 			cfg.Processing.JobTimeoutMinutes = timeout
 		}
 	}
+	if val := os.Getenv("PROCESSING_DRAIN_TIMEOUT_MINUTES"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.DrainTimeoutMinutes = timeout
+		}
+	}
 	if val := os.Getenv("PROCESSING_TEMP_DIR"); val != "" {
 		cfg.Processing.TempDir = val
 	}
@@ -256,6 +407,44 @@ func loadFromEnv(cfg *Config) {
 		}
 	}
 
+	if val := os.Getenv("PROCESSING_JOB_RETENTION_MINUTES"); val != "" {
+		if minutes, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.JobRetentionMinutes = minutes
+		}
+	}
+	if val := os.Getenv("PROCESSING_MAX_RETAINED_JOBS"); val != "" {
+		if jobs, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.MaxRetainedJobs = jobs
+		}
+	}
+
+	if val := os.Getenv("PROCESSING_JOB_STORE_TYPE"); val != "" {
+		cfg.Processing.JobStore.Type = val
+	}
+	if val := os.Getenv("PROCESSING_JOB_STORE_PATH"); val != "" {
+		cfg.Processing.JobStore.Path = val
+	}
+	if val := os.Getenv("PROCESSING_DEDUP_TYPE"); val != "" {
+		cfg.Processing.Dedup.Type = val
+	}
+	if val := os.Getenv("PROCESSING_DEDUP_PATH"); val != "" {
+		cfg.Processing.Dedup.Path = val
+	}
+	if val := os.Getenv("PROCESSING_DEDUP_TTL_MINUTES"); val != "" {
+		if minutes, err := strconv.Atoi(val); err == nil {
+			cfg.Processing.Dedup.TTLMinutes = minutes
+		}
+	}
+	if val := os.Getenv("PROCESSING_DEAD_LETTER_TYPE"); val != "" {
+		cfg.Processing.DeadLetter.Type = val
+	}
+	if val := os.Getenv("PROCESSING_DEAD_LETTER_PATH"); val != "" {
+		cfg.Processing.DeadLetter.Path = val
+	}
+	if val := os.Getenv("PROCESSING_DEAD_LETTER_PREFIX"); val != "" {
+		cfg.Processing.DeadLetter.Prefix = val
+	}
+
 	// FFmpeg config
 	if val := os.Getenv("FFMPEG_BINARY_PATH"); val != "" {
 		cfg.FFmpeg.BinaryPath = val
@@ -309,6 +498,24 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("max concurrent jobs must be positive: %d", cfg.Processing.MaxConcurrentJobs)
 	}
 
+	if cfg.Processing.DrainTimeoutMinutes < 0 {
+		return fmt.Errorf("drain timeout must not be negative: %d", cfg.Processing.DrainTimeoutMinutes)
+	}
+
+	if cfg.Processing.MaxQueuedJobs <= 0 {
+		return fmt.Errorf("max queued jobs must be positive: %d", cfg.Processing.MaxQueuedJobs)
+	}
+
+	if cfg.Processing.MaxParallelEncodes <= 0 {
+		return fmt.Errorf("max parallel encodes must be positive: %d", cfg.Processing.MaxParallelEncodes)
+	}
+
+	// Fewer slots than jobs would leave running jobs unable to encode
+	if cfg.Processing.EncodeSlots < cfg.Processing.MaxConcurrentJobs {
+		return fmt.Errorf("encode slots (%d) must be at least max concurrent jobs (%d)",
+			cfg.Processing.EncodeSlots, cfg.Processing.MaxConcurrentJobs)
+	}
+
 	if cfg.Storage.Type == "" {
 		return fmt.Errorf("storage type is required")
 	}
@@ -325,6 +532,145 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("invalid storage type: %s", cfg.Storage.Type)
 	}
 
+	if cfg.Storage.Type == "s3" && cfg.Storage.S3.Bucket == "" {
+		return fmt.Errorf("s3 bucket is required for s3 storage")
+	}
+
+	validJobStoreTypes := []string{"bolt", "memory"}
+	valid = false
+	for _, t := range validJobStoreTypes {
+		if cfg.Processing.JobStore.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid job store type: %s", cfg.Processing.JobStore.Type)
+	}
+
+	if cfg.Processing.JobStore.Type == "bolt" && cfg.Processing.JobStore.Path == "" {
+		return fmt.Errorf("job store path is required for bolt job store")
+	}
+
+	valid = false
+	for _, t := range validJobStoreTypes {
+		if cfg.Processing.Dedup.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid dedup store type: %s", cfg.Processing.Dedup.Type)
+	}
+
+	if cfg.Processing.Dedup.Type == "bolt" && cfg.Processing.Dedup.Path == "" {
+		return fmt.Errorf("dedup store path is required for bolt dedup store")
+	}
+
+	if cfg.Processing.Dedup.TTLMinutes <= 0 {
+		return fmt.Errorf("dedup ttl must be positive: %d", cfg.Processing.Dedup.TTLMinutes)
+	}
+
+	validDeadLetterTypes := []string{"disk", "storage"}
+	valid = false
+	for _, t := range validDeadLetterTypes {
+		if cfg.Processing.DeadLetter.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid dead letter store type: %s", cfg.Processing.DeadLetter.Type)
+	}
+
+	if cfg.Processing.DeadLetter.Type == "disk" && cfg.Processing.DeadLetter.Path == "" {
+		return fmt.Errorf("dead letter path is required for disk dead letter store")
+	}
+
+	for name, template := range cfg.JobTemplates {
+		notifications := template.Notifications
+		if notifications.WebhookURL != "" &&
+			!strings.HasPrefix(notifications.WebhookURL, "http://") &&
+			!strings.HasPrefix(notifications.WebhookURL, "https://") {
+			return fmt.Errorf("invalid webhook url for job template %s: %s", name, notifications.WebhookURL)
+		}
+		if notifications.MaxRetries < 0 || notifications.TimeoutSeconds < 0 {
+			return fmt.Errorf("notification retries and timeout must not be negative for job template %s", name)
+		}
+
+		if template.ParallelEncodes < 0 {
+			return fmt.Errorf("parallel encodes must not be negative for job template %s", name)
+		}
+
+		retry := template.Retry
+		if retry.MaxAttempts < 0 || retry.InitialBackoffSeconds < 0 || retry.MaxBackoffSeconds < 0 {
+			return fmt.Errorf("retry settings must not be negative for job template %s", name)
+		}
+
+		for _, output := range template.Outputs {
+			switch output.Upscale {
+			case "", "skip", "cap", "allow":
+			default:
+				return fmt.Errorf("invalid upscale policy for output %s of job template %s: %s", output.Name, name, output.Upscale)
+			}
+			if err := validateFit(output.Fit); err != nil {
+				return fmt.Errorf("output %s of job template %s: %w", output.Name, name, err)
+			}
+			if strings.EqualFold(output.Package, "hls") {
+				switch strings.ToLower(output.Container) {
+				case "", "ts", "mpegts", "fmp4", "cmaf":
+				default:
+					return fmt.Errorf("invalid hls container for output %s of job template %s: %s", output.Name, name, output.Container)
+				}
+			}
+			if strings.EqualFold(output.Package, "dash") {
+				switch strings.ToLower(output.Container) {
+				case "", "fmp4", "cmaf":
+				default:
+					return fmt.Errorf("invalid dash container for output %s of job template %s: %s", output.Name, name, output.Container)
+				}
+			} else if output.HLSPlaylist {
+				return fmt.Errorf("output %s of job template %s: hls_playlist is only supported for dash", output.Name, name)
+			}
+			if output.SinglePass && !strings.EqualFold(output.Package, "hls") {
+				return fmt.Errorf("output %s of job template %s: single_pass is only supported for hls", output.Name, name)
+			}
+			if output.AudioOnly && !strings.EqualFold(output.Package, "hls") {
+				return fmt.Errorf("output %s of job template %s: audio_only is only supported for hls", output.Name, name)
+			}
+			for _, track := range output.AudioTracks {
+				if strings.TrimSpace(track) == "" {
+					return fmt.Errorf("output %s of job template %s: audio_tracks must not contain empty entries", output.Name, name)
+				}
+			}
+			if len(output.SubtitleTracks) > 0 && !strings.EqualFold(output.Package, "hls") {
+				return fmt.Errorf("output %s of job template %s: subtitle_tracks is only supported for hls", output.Name, name)
+			}
+			for _, track := range output.SubtitleTracks {
+				if strings.TrimSpace(track) == "" {
+					return fmt.Errorf("output %s of job template %s: subtitle_tracks must not contain empty entries", output.Name, name)
+				}
+			}
+			for _, profile := range output.Profiles {
+				// Single-pass encoding names variant streams after their profile
+				if output.SinglePass && (profile.Name == "" || strings.ContainsAny(profile.Name, " ,:%/")) {
+					return fmt.Errorf("profile %q of output %s of job template %s: single_pass requires profile names without spaces, commas, colons, %% or /",
+						profile.Name, output.Name, name)
+				}
+				if err := validateFit(profile.Fit); err != nil {
+					return fmt.Errorf("profile %s of output %s of job template %s: %w", profile.Name, output.Name, name, err)
+				}
+				if (profile.Fit == "contain" || profile.Fit == "cover") && (profile.Width <= 0 || profile.Height <= 0) {
+					return fmt.Errorf("profile %s of output %s of job template %s: fit %s requires width and height",
+						profile.Name, output.Name, name, profile.Fit)
+				}
+				if err := validateRateControl(&profile); err != nil {
+					return fmt.Errorf("profile %s of output %s of job template %s: %w", profile.Name, output.Name, name, err)
+				}
+			}
+		}
+	}
+
 	validLogLevels := []string{"debug", "info", "warn", "error"}
 	valid = false
 	for _, l := range validLogLevels {
@@ -339,3 +685,34 @@ func validate(cfg *Config) error {
 
 	return nil
 }
+
+// validateFit checks a profile fit mode
+func validateFit(fit string) error {
+	switch fit {
+	case "", "source", "width", "contain", "cover":
+		return nil
+	default:
+		return fmt.Errorf("invalid fit mode: %s", fit)
+	}
+}
+
+// validateRateControl checks a profile's rate control settings; the
+// transcoder checks them against the profile's codec
+func validateRateControl(profile *ProfileConfig) error {
+	if (profile.CRF != nil && *profile.CRF < 0) || profile.MaxBitrateKbps < 0 {
+		return fmt.Errorf("crf and max_bitrate_kbps must not be negative")
+	}
+	switch profile.RateControl {
+	case "", "bitrate", "crf", "capped_crf":
+	case "two_pass", "cbr":
+		if profile.VideoBitrateKbps <= 0 {
+			return fmt.Errorf("rate control %s requires video_bitrate_kbps", profile.RateControl)
+		}
+	default:
+		return fmt.Errorf("invalid rate control: %s", profile.RateControl)
+	}
+	if profile.RateControl == "capped_crf" && profile.MaxBitrateKbps <= 0 && profile.VideoBitrateKbps <= 0 {
+		return fmt.Errorf("rate control capped_crf requires max_bitrate_kbps or video_bitrate_kbps")
+	}
+	return nil
+}
@@ -2,13 +2,14 @@ package events
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -132,36 +133,6 @@ func (r *Router) startWebSocketListener(ctx context.Context) {
 	}
 }
 
-// connectWebSocket establishes a WebSocket connection and listens for events
-func (r *Router) connectWebSocket(ctx context.Context) error {
-	slog.Info("Connecting to WebSocket", "endpoint", r.config.EventSources.WebSocket.Endpoint)
-
-	// For now, this is a placeholder that simulates WebSocket connection
-	// In a real implementation, you would use a WebSocket library like gorilla/websocket
-	// and implement the actual WebSocket client protocol
-
-	// Example structure:
-	// 1. Create WebSocket connection with authentication
-	// 2. Send authentication token if required
-	// 3. Listen for messages in a loop
-	// 4. Parse and process events
-	// 5. Handle connection errors and reconnection
-
-	// Placeholder: simulate connection for 30 seconds
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			// Simulate receiving an event
-			slog.Debug("WebSocket connection active (placeholder)")
-		}
-	}
-}
-
 // handleEventGridWebhook handles incoming Azure Event Grid webhooks
 func (r *Router) handleEventGridWebhook(w http.ResponseWriter, req *http.Request) {
 	slog.Debug("Received Event Grid webhook", "method", req.Method, "remote_addr", req.RemoteAddr)
@@ -199,8 +170,22 @@ func (r *Router) handleEventGridWebhook(w http.ResponseWriter, req *http.Request
 				}
 			}
 
-			// Process actual blob events
+			// Process actual blob events. When the backlog is full, stop and
+			// answer 429 so Event Grid redelivers the batch later instead of
+			// the events being dropped.
 			if err := r.processEventGridEvent(event); err != nil {
+				if errors.Is(err, worker.ErrQueueFull) {
+					slog.Warn("Job queue is full, asking Event Grid to retry", "error", err)
+					w.Header().Set("Retry-After", strconv.Itoa(int(worker.QueueFullRetryAfter.Seconds())))
+					http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+					return
+				}
+				if errors.Is(err, worker.ErrDraining) {
+					// Event Grid retries 503s, reaching an instance that is not draining
+					slog.Warn("Draining, asking Event Grid to retry", "error", err)
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
 				slog.Error("Failed to process Event Grid event", "error", err)
 			}
 		}
@@ -255,7 +240,7 @@ func (r *Router) handleBlobCreatedEvent(event map[string]interface{}) error {
 
 	// Create conversion job using default template
 	job := &models.ConversionJob{
-		JobID:    generateJobID(),
+		JobID:    worker.GenerateJobID(),
 		VideoID:  videoId,
 		Template: "default", // Use default template from config
 		Source: models.SourceConfig{
@@ -269,10 +254,28 @@ func (r *Router) handleBlobCreatedEvent(event map[string]interface{}) error {
 	job.Status.Progress = 0.0
 	job.CreatedAt = time.Now()
 
+	// Event Grid delivers at least once: a redelivered event keeps its ID,
+	// and the same blob version keeps its ETag even if it raised a new event
+	var keys []string
+	if eventID, _ := event["id"].(string); eventID != "" {
+		keys = append(keys, worker.EventGridDedupKey(eventID))
+	}
+	if eTag, _ := data["eTag"].(string); eTag != "" {
+		keys = append(keys, worker.SourceDedupKey(blobUrl, eTag))
+	}
+
 	// Submit job to worker
-	if err := r.worker.SubmitJob(job); err != nil {
+	submitted, duplicate, err := r.worker.SubmitJobOnce(job, keys...)
+	if err != nil {
 		return fmt.Errorf("failed to submit job: %w", err)
 	}
+	if duplicate {
+		slog.Info("Ignoring duplicate Event Grid event",
+			"existingJobId", submitted.JobID,
+			"sourceUrl", blobUrl,
+		)
+		return nil
+	}
 
 	slog.Info("Submitted conversion job from Event Grid",
 		"jobId", job.JobID,
@@ -355,17 +358,3 @@ func extractVideoIdFromUrl(blobUrl string) string {
 
 	return videoId
 }
-
-// generateJobID generates a unique job ID using timestamp and random bytes
-func generateJobID() string {
-	// Generate 4 random bytes for uniqueness
-	randomBytes := make([]byte, 4)
-	if _, err := rand.Read(randomBytes); err != nil {
-		// Fallback to timestamp-based ID if random generation fails
-		return fmt.Sprintf("job-%d", time.Now().UnixNano())
-	}
-
-	// Combine timestamp with random bytes for uniqueness
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("job-%d-%x", timestamp, randomBytes)
-}
@@ -0,0 +1,397 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+const (
+	wsHandshakeTimeout = 30 * time.Second
+	wsWriteWait        = 10 * time.Second
+	wsPongWait         = 60 * time.Second
+	wsPingPeriod       = wsPongWait * 9 / 10
+	wsMaxMessageSize   = 1 << 20
+	wsSendBuffer       = 64
+	wsUpdateBuffer     = 256
+)
+
+// connectWebSocket establishes a WebSocket connection and processes events
+// until the connection closes or the context is cancelled
+func (r *Router) connectWebSocket(ctx context.Context) error {
+	wsConfig := r.config.EventSources.WebSocket
+	slog.Info("Connecting to WebSocket", "endpoint", wsConfig.Endpoint)
+
+	header := http.Header{}
+	if wsConfig.Token != "" {
+		header.Set("Authorization", "Bearer "+wsConfig.Token)
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+	}
+	conn, resp, err := dialer.DialContext(ctx, wsConfig.Endpoint, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("websocket handshake failed with status %d: %w", resp.StatusCode, err)
+		}
+		return fmt.Errorf("failed to connect to websocket: %w", err)
+	}
+
+	slog.Info("WebSocket connected", "endpoint", wsConfig.Endpoint)
+
+	session := newWSSession(conn, r.worker, "client")
+	if err := session.run(ctx); err != nil {
+		slog.Warn("WebSocket connection lost", "endpoint", wsConfig.Endpoint, "error", err)
+	} else {
+		slog.Info("WebSocket connection closed", "endpoint", wsConfig.Endpoint)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// wsSession exchanges events and job updates with one WebSocket peer.
+// The peer submits jobs with convert-request events; every job submitted on
+// the connection is acknowledged and its status changes are sent back keyed
+// by the event's correlation ID until the job finishes. The peer may also
+// subscribe to status updates for other jobs, or for all jobs.
+type wsSession struct {
+	conn   *websocket.Conn
+	worker *worker.Worker
+	role   string
+	send   chan models.WebSocketMessage
+
+	// mu guards the fields below and orders acks before the status updates
+	// that follow them
+	mu            sync.Mutex
+	jobs          map[string]string // jobID -> correlationID
+	subscriptions map[string]bool
+	subscribeAll  bool
+}
+
+// newWSSession creates a session for an established connection
+func newWSSession(conn *websocket.Conn, w *worker.Worker, role string) *wsSession {
+	return &wsSession{
+		conn:   conn,
+		worker: w,
+		role:   role,
+		send:   make(chan models.WebSocketMessage, wsSendBuffer),
+		jobs:   make(map[string]string),
+
+		subscriptions: make(map[string]bool),
+	}
+}
+
+// run serves the connection until it closes or the context is cancelled.
+// A normal close by the peer is not an error.
+func (s *wsSession) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates, unsubscribe := s.worker.Subscribe(wsUpdateBuffer)
+	defer unsubscribe()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.forwardUpdates(ctx, updates)
+	}()
+	go func() {
+		defer wg.Done()
+		// A failed write ends the session so the reader is not left blocked
+		defer cancel()
+		s.writeLoop(ctx)
+	}()
+
+	err := s.readLoop(ctx)
+	cancel()
+	wg.Wait()
+
+	return err
+}
+
+// readLoop reads events from the peer until the connection fails
+func (s *wsSession) readLoop(ctx context.Context) error {
+	s.conn.SetReadLimit(wsMaxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil ||
+				websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var event models.WebSocketEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			s.reply(ctx, models.WebSocketMessage{
+				Type:  models.WebSocketMessageNack,
+				Error: fmt.Sprintf("invalid event: %v", err),
+			})
+			continue
+		}
+
+		s.handleEvent(ctx, &event)
+	}
+}
+
+// handleEvent dispatches a single event from the peer
+func (s *wsSession) handleEvent(ctx context.Context, event *models.WebSocketEvent) {
+	switch event.Type {
+	case models.WebSocketEventConvertRequest:
+		s.submitJob(ctx, event)
+	case models.WebSocketEventSubscribe:
+		s.subscribe(ctx, event)
+	case models.WebSocketEventUnsubscribe:
+		s.unsubscribe(ctx, event)
+	default:
+		s.reply(ctx, models.WebSocketMessage{
+			Type:          models.WebSocketMessageNack,
+			CorrelationID: event.CorrelationID,
+			Error:         fmt.Sprintf("unsupported event type: %s", event.Type),
+		})
+	}
+}
+
+// submitJob submits the event's job and replies with an ack or nack
+func (s *wsSession) submitJob(ctx context.Context, event *models.WebSocketEvent) {
+	job := event.Job
+	if job.CorrelationID == "" {
+		job.CorrelationID = event.CorrelationID
+	}
+
+	nack := func(err error) {
+		slog.Warn("Rejected WebSocket job",
+			"role", s.role,
+			"correlationId", event.CorrelationID,
+			"jobId", job.JobID,
+			"error", err,
+		)
+		message := models.WebSocketMessage{
+			Type:          models.WebSocketMessageNack,
+			CorrelationID: event.CorrelationID,
+			JobID:         job.JobID,
+			Error:         err.Error(),
+		}
+		if errors.Is(err, worker.ErrQueueFull) {
+			message.RetryAfter = int(worker.QueueFullRetryAfter.Seconds())
+		}
+		s.reply(ctx, message)
+	}
+
+	if err := s.worker.ValidateJob(&job); err != nil {
+		nack(err)
+		return
+	}
+	if job.JobID == "" {
+		job.JobID = worker.GenerateJobID()
+	}
+
+	// Hold the lock until the ack is queued so status updates for the job
+	// cannot overtake it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	if event.IdempotencyKey != "" {
+		keys = append(keys, worker.IdempotencyDedupKey(event.IdempotencyKey))
+	}
+	if job.Source.Checksum != "" {
+		keys = append(keys, worker.SourceDedupKey(job.Source.URI, job.Source.Checksum))
+	}
+
+	submitted, duplicate, err := s.worker.SubmitJobOnce(&job, keys...)
+	if err != nil {
+		nack(err)
+		return
+	}
+	// A duplicate of a finished job gets no further updates to untrack it
+	if !submitted.Status.State.IsTerminal() {
+		s.jobs[submitted.JobID] = event.CorrelationID
+	}
+
+	if !duplicate {
+		slog.Info("Submitted conversion job from WebSocket",
+			"role", s.role,
+			"jobId", job.JobID,
+			"correlationId", event.CorrelationID,
+			"videoId", job.VideoID,
+			"template", job.Template,
+		)
+	}
+
+	// A duplicate is acked with the existing job so the peer follows it
+	status := submitted.Status
+	s.reply(ctx, models.WebSocketMessage{
+		Type:          models.WebSocketMessageAck,
+		CorrelationID: event.CorrelationID,
+		JobID:         submitted.JobID,
+		Status:        &status,
+	})
+}
+
+// subscribe starts sending status updates for one job, or for all jobs if
+// the event has no job ID. The ack carries the job's current status.
+func (s *wsSession) subscribe(ctx context.Context, event *models.WebSocketEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.JobID == "" {
+		s.subscribeAll = true
+		s.reply(ctx, models.WebSocketMessage{
+			Type:          models.WebSocketMessageAck,
+			CorrelationID: event.CorrelationID,
+		})
+		return
+	}
+
+	job, err := s.worker.GetJob(event.JobID)
+	if err != nil {
+		s.reply(ctx, models.WebSocketMessage{
+			Type:          models.WebSocketMessageNack,
+			CorrelationID: event.CorrelationID,
+			JobID:         event.JobID,
+			Error:         err.Error(),
+		})
+		return
+	}
+
+	if !job.Status.State.IsTerminal() {
+		s.subscriptions[job.JobID] = true
+	}
+	s.reply(ctx, models.WebSocketMessage{
+		Type:          models.WebSocketMessageAck,
+		CorrelationID: event.CorrelationID,
+		JobID:         job.JobID,
+		Status:        &job.Status,
+	})
+}
+
+// unsubscribe stops status updates for one job, or for every subscription
+// if the event has no job ID. Jobs submitted on the connection still report
+// their status.
+func (s *wsSession) unsubscribe(ctx context.Context, event *models.WebSocketEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.JobID == "" {
+		s.subscribeAll = false
+		clear(s.subscriptions)
+	} else {
+		delete(s.subscriptions, event.JobID)
+	}
+
+	s.reply(ctx, models.WebSocketMessage{
+		Type:          models.WebSocketMessageAck,
+		CorrelationID: event.CorrelationID,
+		JobID:         event.JobID,
+	})
+}
+
+// route reports whether an update should be sent to the peer and with which
+// correlation ID. Finished jobs stop being tracked.
+func (s *wsSession) route(update *models.JobUpdate) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	correlationID, submitted := s.jobs[update.JobID]
+	if !submitted {
+		correlationID = update.CorrelationID
+	}
+	wanted := submitted || s.subscribeAll || s.subscriptions[update.JobID]
+
+	if update.Status.State.IsTerminal() {
+		delete(s.jobs, update.JobID)
+		delete(s.subscriptions, update.JobID)
+	}
+
+	return correlationID, wanted
+}
+
+// forwardUpdates relays status updates for jobs submitted or subscribed to
+// on this connection
+func (s *wsSession) forwardUpdates(ctx context.Context, updates <-chan models.JobUpdate) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			correlationID, wanted := s.route(&update)
+			if !wanted {
+				continue
+			}
+
+			status := update.Status
+			s.reply(ctx, models.WebSocketMessage{
+				Type:          models.WebSocketMessageStatus,
+				CorrelationID: correlationID,
+				JobID:         update.JobID,
+				Status:        &status,
+			})
+		}
+	}
+}
+
+// reply queues a message for the peer
+func (s *wsSession) reply(ctx context.Context, message models.WebSocketMessage) {
+	message.Timestamp = time.Now()
+	select {
+	case s.send <- message:
+	case <-ctx.Done():
+	}
+}
+
+// writeLoop writes queued messages and keep-alive pings to the peer. It is
+// the only goroutine that writes to the connection.
+func (s *wsSession) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	defer s.conn.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"),
+				time.Now().Add(wsWriteWait))
+			return
+		case message := <-s.send:
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := s.conn.WriteJSON(message); err != nil {
+				slog.Warn("Failed to write WebSocket message", "role", s.role, "error", err)
+				return
+			}
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				slog.Warn("Failed to send WebSocket ping", "role", s.role, "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+)
+
+// WebSocketServer accepts WebSocket connections from clients that submit
+// jobs and follow job progress. It speaks the same protocol as the
+// router's WebSocket client.
+type WebSocketServer struct {
+	ctx      context.Context
+	config   *config.Config
+	worker   *worker.Worker
+	upgrader websocket.Upgrader
+}
+
+// NewWebSocketServer creates a WebSocket endpoint handler. Open connections
+// are closed when ctx is cancelled.
+func NewWebSocketServer(ctx context.Context, cfg *config.Config, w *worker.Worker) *WebSocketServer {
+	if cfg.Server.EventsToken == "" {
+		slog.Warn("No events token configured, WebSocket connections to /events will be rejected")
+	}
+
+	return &WebSocketServer{
+		ctx:    ctx,
+		config: cfg,
+		worker: w,
+		upgrader: websocket.Upgrader{
+			// Connections are authenticated with the events token, so browser
+			// clients such as the admin UI may connect from any origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeHTTP authenticates the request and upgrades it to a WebSocket session
+func (ws *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ws.config.Server.EventsToken == "" {
+		http.Error(w, "WebSocket events endpoint is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if !ws.authenticate(r) {
+		slog.Warn("WebSocket authentication failed", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := ws.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		slog.Warn("WebSocket upgrade failed", "remote_addr", r.RemoteAddr, "error", err)
+		return
+	}
+
+	slog.Info("WebSocket client connected", "remote_addr", r.RemoteAddr)
+
+	// End the session on client disconnect or service shutdown
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(ws.ctx, cancel)
+	defer stop()
+
+	session := newWSSession(conn, ws.worker, "server")
+	if err := session.run(ctx); err != nil {
+		slog.Warn("WebSocket client connection lost", "remote_addr", r.RemoteAddr, "error", err)
+		return
+	}
+	slog.Info("WebSocket client disconnected", "remote_addr", r.RemoteAddr)
+}
+
+// authenticate checks the bearer token from the Authorization header, or
+// the access_token query parameter for browsers that cannot set headers on
+// WebSocket requests
+func (ws *WebSocketServer) authenticate(r *http.Request) bool {
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(ws.config.Server.EventsToken)) == 1
+}
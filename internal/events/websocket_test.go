@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/worker"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// newTestWorker starts a worker whose jobs fail fast at download time
+func newTestWorker(t *testing.T, ctx context.Context) (*config.Config, *worker.Worker) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Server: config.ServerConfig{EventsToken: "secret"},
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			JobTimeoutMinutes: 1,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage: config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		// The binary is only run with -version at startup
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	}
+
+	w, err := worker.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+	go w.Start(ctx)
+
+	return cfg, w
+}
+
+func TestWebSocketServer_SubmitAndStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, w := newTestWorker(t, ctx)
+	server := httptest.NewServer(NewWebSocketServer(ctx, cfg, w))
+	defer server.Close()
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	if _, resp, err := websocket.DefaultDialer.Dial(endpoint, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected unauthenticated connection to be rejected, got %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(endpoint+"?access_token=secret", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	read := func() models.WebSocketMessage {
+		t.Helper()
+		var message models.WebSocketMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return message
+	}
+
+	conn.WriteJSON(models.WebSocketEvent{Type: "unknown", CorrelationID: "c-0"})
+	if message := read(); message.Type != models.WebSocketMessageNack || message.CorrelationID != "c-0" {
+		t.Fatalf("Expected nack for unknown event, got %+v", message)
+	}
+
+	conn.WriteJSON(models.WebSocketEvent{
+		Type:          models.WebSocketEventConvertRequest,
+		CorrelationID: "c-1",
+		Job: models.ConversionJob{
+			VideoID: "video-1",
+			Source:  models.SourceConfig{URI: "/does/not/exist.mp4", Type: "local"},
+		},
+	})
+	ack := read()
+	if ack.Type != models.WebSocketMessageAck || ack.CorrelationID != "c-1" || ack.JobID == "" {
+		t.Fatalf("Expected ack, got %+v", ack)
+	}
+
+	// Status frames follow until the job fails on the missing source
+	for {
+		message := read()
+		if message.Type != models.WebSocketMessageStatus || message.CorrelationID != "c-1" || message.JobID != ack.JobID {
+			t.Fatalf("Unexpected message: %+v", message)
+		}
+		if message.Status.State == models.JobStateFailed {
+			break
+		}
+	}
+}
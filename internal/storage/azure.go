@@ -71,7 +71,7 @@ func (as *AzureStorage) initializeClient() error {
 }
 
 // DownloadFile downloads a file from Azure Blob Storage
-func (as *AzureStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string) (string, error) {
+func (as *AzureStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string, name string) (string, error) {
 	// Parse Azure Blob URL
 	storageAccount, containerName, blobName, err := as.parseAzureBlobURL(sourceURI)
 	if err != nil {
@@ -86,7 +86,7 @@ func (as *AzureStorage) DownloadFile(ctx context.Context, sourceURI string, jobI
 
 	// Create temp file path
 	ext := filepath.Ext(blobName)
-	tempFilePath := filepath.Join(tempDir, "source"+ext)
+	tempFilePath := filepath.Join(tempDir, name+ext)
 
 	slog.Info("Azure Blob download details",
 		"jobId", jobID,
@@ -134,7 +134,7 @@ func (as *AzureStorage) UploadFile(ctx context.Context, sourcePath string, desti
 	// Upload to Azure Blob
 	_, err = as.client.UploadStream(ctx, as.container, destinationPath, file, nil)
 	if err != nil {
-		return fmt.Errorf("failed to upload to Azure Blob: %w", err)
+		return fmt.Errorf("failed to upload to Azure Blob: %w", withStatus(err))
 	}
 
 	slog.Info("Successfully uploaded file to Azure Blob Storage",
@@ -247,7 +247,7 @@ func (as *AzureStorage) downloadAuthenticatedBlob(ctx context.Context, container
 	// Download the blob
 	response, err := as.client.DownloadStream(ctx, containerName, blobName, nil)
 	if err != nil {
-		return fmt.Errorf("failed to download blob via Azure SDK: %w", err)
+		return fmt.Errorf("failed to download blob via Azure SDK: %w", withStatus(err))
 	}
 	defer response.Body.Close()
 
@@ -284,7 +284,10 @@ func (as *AzureStorage) downloadPublicBlob(ctx context.Context, blobURI, tempFil
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("blob download failed with status: %d", resp.StatusCode)
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("blob download failed with status: %d", resp.StatusCode),
+		}
 	}
 
 	// Create output file
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// StatusError reports a storage request that failed with an HTTP status code
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request may succeed if repeated later.
+// Timeouts, throttling and server errors are transient; other statuses such
+// as 403 or 404 will fail the same way again.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// withStatus wraps SDK response errors in a StatusError so callers can tell
+// transient failures from permanent ones. Other errors are returned as is.
+func withStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var azureErr *azcore.ResponseError
+	if errors.As(err, &azureErr) {
+		return &StatusError{StatusCode: azureErr.StatusCode, Err: err}
+	}
+
+	// AWS SDK response errors expose the status through this method
+	var awsErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &awsErr) {
+		return &StatusError{StatusCode: awsErr.HTTPStatusCode(), Err: err}
+	}
+
+	return err
+}
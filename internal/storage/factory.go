@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/matt-primrose/video-converter-service/internal/config"
 )
@@ -21,11 +22,7 @@ func NewStorage(cfg *config.Config) (Storage, error) {
 		return NewAzureStorage(cfg.Storage.AzureBlob, storageConfig)
 
 	case "s3":
-		s3Config := S3Config{
-			Bucket: cfg.Storage.S3.Bucket,
-			Region: cfg.Storage.S3.Region,
-		}
-		return NewS3Storage(s3Config, storageConfig)
+		return NewS3Storage(newS3Config(cfg, cfg.Storage.S3.Bucket), storageConfig)
 
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Storage.Type)
@@ -57,11 +54,8 @@ func NewDownloadOnlyStorage(sourceType string, cfg *config.Config) (Storage, err
 		return NewAzureStorage(azureConfig, storageConfig)
 
 	case "s3":
-		s3Config := S3Config{
-			Bucket: "", // Bucket will be parsed from URL
-			Region: cfg.Storage.S3.Region,
-		}
-		return NewS3Storage(s3Config, storageConfig)
+		// Bucket will be parsed from URL; endpoint and credentials are shared with output storage
+		return NewS3Storage(newS3Config(cfg, ""), storageConfig)
 
 	case "http", "https":
 		// For HTTP downloads, use HTTP storage implementation
@@ -71,3 +65,17 @@ func NewDownloadOnlyStorage(sourceType string, cfg *config.Config) (Storage, err
 		return nil, fmt.Errorf("unsupported source type for download: %s", sourceType)
 	}
 }
+
+// newS3Config builds the S3 backend configuration for the given bucket
+func newS3Config(cfg *config.Config, bucket string) S3Config {
+	return S3Config{
+		Bucket:          bucket,
+		Region:          cfg.Storage.S3.Region,
+		Endpoint:        cfg.Storage.S3.Endpoint,
+		UsePathStyle:    cfg.Storage.S3.UsePathStyle,
+		AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+		SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+		SessionToken:    cfg.Storage.S3.SessionToken,
+		PresignExpiry:   time.Duration(cfg.Storage.S3.PresignExpiryMinutes) * time.Minute,
+	}
+}
@@ -26,7 +26,7 @@ func NewHTTPStorage(config StorageConfig) *HTTPStorage {
 }
 
 // DownloadFile downloads a file from HTTP/HTTPS URL
-func (hs *HTTPStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string) (string, error) {
+func (hs *HTTPStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string, name string) (string, error) {
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", sourceURI, nil)
 	if err != nil {
@@ -45,7 +45,10 @@ func (hs *HTTPStorage) DownloadFile(ctx context.Context, sourceURI string, jobID
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP request failed with status: %d %s", resp.StatusCode, resp.Status)
+		return "", &StatusError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("HTTP request failed with status: %s", resp.Status),
+		}
 	}
 
 	// Create temp directory for this job
@@ -56,7 +59,7 @@ func (hs *HTTPStorage) DownloadFile(ctx context.Context, sourceURI string, jobID
 
 	// Determine file extension from URL or Content-Type
 	ext := hs.getFileExtension(sourceURI, resp.Header.Get("Content-Type"))
-	tempFile := filepath.Join(tempDir, "source"+ext)
+	tempFile := filepath.Join(tempDir, name+ext)
 
 	// Create output file
 	outFile, err := os.Create(tempFile)
@@ -7,8 +7,9 @@ import (
 // Storage defines the interface for different storage backends
 type Storage interface {
 	// DownloadFile downloads a file from the storage backend to a local temporary file
+	// in the job's temp directory, named name plus the source file's extension
 	// Returns the local file path and any error
-	DownloadFile(ctx context.Context, sourceURI string, jobID string) (string, error)
+	DownloadFile(ctx context.Context, sourceURI string, jobID string, name string) (string, error)
 
 	// UploadFile uploads a local file to the storage backend
 	// sourcePath is the local file path, destinationPath is the target path in storage
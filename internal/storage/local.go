@@ -25,7 +25,7 @@ func NewLocalStorage(basePath string, config StorageConfig) *LocalStorage {
 }
 
 // DownloadFile for local storage means copying from one local path to temp directory
-func (ls *LocalStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string) (string, error) {
+func (ls *LocalStorage) DownloadFile(ctx context.Context, sourceURI string, jobID string, name string) (string, error) {
 	// Remove file:// prefix if present
 	localPath := strings.TrimPrefix(sourceURI, "file://")
 
@@ -42,7 +42,7 @@ func (ls *LocalStorage) DownloadFile(ctx context.Context, sourceURI string, jobI
 
 	// Get file extension and create temp file path
 	ext := filepath.Ext(localPath)
-	tempFile := filepath.Join(tempDir, "source"+ext)
+	tempFile := filepath.Join(tempDir, name+ext)
 
 	// Copy file
 	if err := ls.copyFile(localPath, tempFile); err != nil {
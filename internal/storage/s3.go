@@ -3,148 +3,371 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-// S3Storage implements the Storage interface for Amazon S3
+// S3Storage implements the Storage interface for Amazon S3 and S3-compatible stores (e.g. MinIO)
 type S3Storage struct {
-	config StorageConfig
-	bucket string
-	region string
-	// TODO: Add AWS SDK client when implementing
+	config        StorageConfig
+	bucket        string
+	region        string
+	endpoint      string
+	usePathStyle  bool
+	presignExpiry time.Duration
+	client        *s3.Client
+	uploader      *manager.Uploader
+	presigner     *s3.PresignClient
 }
 
 // S3Config contains S3 specific configuration
 type S3Config struct {
-	Bucket string
-	Region string
-	// TODO: Add AWS credentials fields
+	Bucket          string
+	Region          string
+	Endpoint        string // Custom endpoint for S3-compatible stores, e.g. http://minio:9000
+	UsePathStyle    bool   // Address buckets as endpoint/bucket/key instead of bucket.endpoint/key
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PresignExpiry   time.Duration
 }
 
 // NewS3Storage creates a new S3 storage instance
 func NewS3Storage(s3Config S3Config, storageConfig StorageConfig) (*S3Storage, error) {
+	// Set defaults
+	region := s3Config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	presignExpiry := s3Config.PresignExpiry
+	if presignExpiry <= 0 {
+		presignExpiry = time.Hour
+	}
+
 	storage := &S3Storage{
-		config: storageConfig,
-		bucket: s3Config.Bucket,
-		region: s3Config.Region,
+		config:        storageConfig,
+		bucket:        s3Config.Bucket,
+		region:        region,
+		endpoint:      strings.TrimSuffix(s3Config.Endpoint, "/"),
+		usePathStyle:  s3Config.UsePathStyle,
+		presignExpiry: presignExpiry,
+	}
+
+	options := s3.Options{
+		Region:       region,
+		Credentials:  s3Credentials(s3Config),
+		UsePathStyle: s3Config.UsePathStyle,
+		// Only send checksums when required so S3-compatible stores that do not
+		// support the newer default integrity headers keep working
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	}
+	if storage.endpoint != "" {
+		options.BaseEndpoint = aws.String(storage.endpoint)
 	}
 
-	// TODO: Initialize AWS S3 client
-	slog.Warn("S3 storage implementation is placeholder - not yet implemented")
+	storage.client = s3.New(options)
+	storage.uploader = manager.NewUploader(storage.client)
+	storage.presigner = s3.NewPresignClient(storage.client, s3.WithPresignExpires(presignExpiry))
 
 	return storage, nil
 }
 
-// DownloadFile downloads a file from S3 (placeholder implementation)
-func (s3 *S3Storage) DownloadFile(ctx context.Context, sourceURI string, jobID string) (string, error) {
-	// TODO: Implement S3 download using AWS SDK
+// s3Credentials returns the credentials provider for the configured keys,
+// falling back to the standard AWS environment variables and finally
+// anonymous access for public buckets
+func s3Credentials(s3Config S3Config) aws.CredentialsProvider {
+	accessKeyID := s3Config.AccessKeyID
+	secretAccessKey := s3Config.SecretAccessKey
+	sessionToken := s3Config.SessionToken
 
+	if accessKeyID == "" && secretAccessKey == "" {
+		accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
+	if accessKeyID == "" || secretAccessKey == "" {
+		slog.Warn("No S3 credentials configured, using anonymous access")
+		return aws.AnonymousCredentials{}
+	}
+
+	credentials := aws.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+		Source:          "video-converter-config",
+	}
+	return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return credentials, nil
+	})
+}
+
+// DownloadFile downloads a file from S3
+func (s3s *S3Storage) DownloadFile(ctx context.Context, sourceURI string, jobID string, name string) (string, error) {
 	// Parse S3 URL to extract bucket and key
-	bucketName, objectKey, err := s3.parseS3URL(sourceURI)
+	bucketName, objectKey, err := s3s.parseS3URL(sourceURI)
 	if err != nil {
 		return "", fmt.Errorf("invalid S3 URI: %w", err)
 	}
 
 	// Create temp directory for this job
-	tempDir := filepath.Join(s3.config.TempDir, jobID)
+	tempDir := filepath.Join(s3s.config.TempDir, jobID)
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	// Create temp file path
 	ext := filepath.Ext(objectKey)
-	tempFilePath := filepath.Join(tempDir, "source"+ext)
+	tempFilePath := filepath.Join(tempDir, name+ext)
 
-	slog.Info("S3 download details (placeholder)",
+	slog.Info("S3 download details",
 		"jobId", jobID,
 		"bucket", bucketName,
 		"objectKey", objectKey,
 		"tempPath", tempFilePath,
 	)
 
-	// TODO: Implement actual S3 download
-	return "", fmt.Errorf("S3 download not yet implemented")
+	response, err := s3s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download S3 object: %w", withStatus(err))
+	}
+	defer response.Body.Close()
+
+	// Create output file
+	outFile, err := os.Create(tempFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	// Copy data, leaving no partial file behind if the transfer fails
+	bytesWritten, err := io.Copy(outFile, response.Body)
+	if err != nil {
+		outFile.Close()
+		os.Remove(tempFilePath)
+		return "", fmt.Errorf("failed to write S3 object data: %w", err)
+	}
+	if err := outFile.Close(); err != nil {
+		os.Remove(tempFilePath)
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	slog.Info("Successfully downloaded S3 object",
+		"jobId", jobID,
+		"objectKey", objectKey,
+		"tempPath", tempFilePath,
+		"size", bytesWritten,
+	)
+
+	return tempFilePath, nil
 }
 
-// UploadFile uploads a file to S3 (placeholder implementation)
-func (s3 *S3Storage) UploadFile(ctx context.Context, sourcePath string, destinationPath string) error {
-	// TODO: Implement S3 upload using AWS SDK
-	slog.Info("S3 upload (placeholder)",
+// UploadFile uploads a file to S3. Files larger than the uploader's part
+// size are sent as a multipart upload with parts uploaded concurrently, so
+// renditions above the 5 GB single PUT limit can be stored.
+func (s3s *S3Storage) UploadFile(ctx context.Context, sourcePath string, destinationPath string) error {
+	if s3s.bucket == "" {
+		return fmt.Errorf("S3 bucket not configured")
+	}
+
+	// Open source file
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer file.Close()
+
+	objectKey := s3ObjectKey(destinationPath)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s3s.bucket),
+		Key:    aws.String(objectKey),
+		Body:   file,
+	}
+	if contentType := contentTypeForPath(sourcePath); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s3s.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", withStatus(err))
+	}
+
+	slog.Info("Successfully uploaded file to S3",
 		"sourcePath", sourcePath,
-		"bucket", s3.bucket,
-		"objectKey", destinationPath,
+		"bucket", s3s.bucket,
+		"objectKey", objectKey,
 	)
 
-	return fmt.Errorf("S3 upload not yet implemented")
+	return nil
 }
 
 // UploadFiles uploads multiple files to S3
-func (s3 *S3Storage) UploadFiles(ctx context.Context, fileMap map[string]string) error {
+func (s3s *S3Storage) UploadFiles(ctx context.Context, fileMap map[string]string) error {
 	for sourcePath, destinationPath := range fileMap {
-		if err := s3.UploadFile(ctx, sourcePath, destinationPath); err != nil {
+		if err := s3s.UploadFile(ctx, sourcePath, destinationPath); err != nil {
 			return fmt.Errorf("failed to upload file %s: %w", sourcePath, err)
 		}
 	}
 	return nil
 }
 
-// GetFileURL returns a public URL for the S3 object
-func (s3 *S3Storage) GetFileURL(destinationPath string) (string, error) {
-	// TODO: Generate proper S3 URL or pre-signed URL
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
-		s3.bucket,
-		s3.region,
-		destinationPath,
-	), nil
+// GetFileURL returns a presigned GET URL for the S3 object
+func (s3s *S3Storage) GetFileURL(destinationPath string) (string, error) {
+	request, err := s3s.presigner.PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s3s.bucket),
+		Key:    aws.String(s3ObjectKey(destinationPath)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 URL: %w", err)
+	}
+
+	return request.URL, nil
 }
 
-// DeleteFile deletes a file from S3 (placeholder implementation)
-func (s3 *S3Storage) DeleteFile(ctx context.Context, destinationPath string) error {
-	// TODO: Implement S3 delete using AWS SDK
-	slog.Debug("S3 delete (placeholder)",
-		"bucket", s3.bucket,
-		"objectKey", destinationPath,
+// DeleteFile deletes a file from S3
+func (s3s *S3Storage) DeleteFile(ctx context.Context, destinationPath string) error {
+	objectKey := s3ObjectKey(destinationPath)
+
+	_, err := s3s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s3s.bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete S3 object: %w", err)
+	}
+
+	slog.Debug("Deleted file from S3",
+		"bucket", s3s.bucket,
+		"objectKey", objectKey,
 	)
 
-	return fmt.Errorf("S3 delete not yet implemented")
+	return nil
 }
 
-// ListFiles lists files in S3 with a prefix (placeholder implementation)
-func (s3 *S3Storage) ListFiles(ctx context.Context, prefix string) ([]string, error) {
-	// TODO: Implement S3 list using AWS SDK
-	slog.Debug("S3 list files (placeholder)",
-		"bucket", s3.bucket,
-		"prefix", prefix,
-	)
+// ListFiles lists files in S3 with a prefix
+func (s3s *S3Storage) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	var files []string
+	paginator := s3.NewListObjectsV2Paginator(s3s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s3s.bucket),
+		Prefix: aws.String(s3ObjectKey(prefix)),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list S3 objects: %w", err)
+		}
 
-	return nil, fmt.Errorf("S3 list files not yet implemented")
+		for _, object := range page.Contents {
+			if object.Key != nil {
+				files = append(files, *object.Key)
+			}
+		}
+	}
+
+	return files, nil
 }
 
 // GetType returns the storage type
-func (s3 *S3Storage) GetType() string {
+func (s3s *S3Storage) GetType() string {
 	return "s3"
 }
 
 // parseS3URL parses an S3 URL and extracts bucket and object key
-func (s3 *S3Storage) parseS3URL(s3URI string) (bucket, objectKey string, err error) {
+func (s3s *S3Storage) parseS3URL(s3URI string) (bucket, objectKey string, err error) {
 	// Handle different S3 URL formats:
 	// - s3://bucket/key
-	// - https://bucket.s3.region.amazonaws.com/key
-	// - https://s3.region.amazonaws.com/bucket/key
+	// - https://bucket.s3.region.amazonaws.com/key (virtual-hosted style)
+	// - https://s3.region.amazonaws.com/bucket/key (path style)
+	// - http://minio:9000/bucket/key (custom endpoint, path style)
 
 	if strings.HasPrefix(s3URI, "s3://") {
 		// s3://bucket/key format
 		path := strings.TrimPrefix(s3URI, "s3://")
 		parts := strings.SplitN(path, "/", 2)
-		if len(parts) < 2 {
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
 			return "", "", fmt.Errorf("invalid s3:// URL format")
 		}
 		return parts[0], parts[1], nil
 	}
 
-	// TODO: Handle HTTPS S3 URLs
-	return "", "", fmt.Errorf("S3 URL parsing not fully implemented - use s3:// format")
+	parsedURL, err := url.Parse(s3URI)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return "", "", fmt.Errorf("unsupported S3 URL scheme: %s", parsedURL.Scheme)
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	path := strings.TrimPrefix(parsedURL.Path, "/")
+
+	// Virtual-hosted style: the bucket is the host label before ".s3"
+	if !s3s.isPathStyleHost(host) {
+		if idx := strings.Index(host, ".s3."); idx > 0 {
+			if path == "" {
+				return "", "", fmt.Errorf("missing object key in S3 URL")
+			}
+			return host[:idx], path, nil
+		}
+		if idx := strings.Index(host, ".s3-"); idx > 0 {
+			if path == "" {
+				return "", "", fmt.Errorf("missing object key in S3 URL")
+			}
+			return host[:idx], path, nil
+		}
+	}
+
+	// Path style: the first path segment is the bucket
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid S3 path-style URL format")
+	}
+	return parts[0], parts[1], nil
+}
+
+// isPathStyleHost reports whether URLs for the host address the bucket in the path
+func (s3s *S3Storage) isPathStyleHost(host string) bool {
+	if s3s.endpoint != "" {
+		if endpointURL, err := url.Parse(s3s.endpoint); err == nil &&
+			strings.EqualFold(endpointURL.Hostname(), host) {
+			return true
+		}
+	}
+
+	// Regional and global AWS endpoints without a bucket prefix
+	return strings.HasPrefix(host, "s3.") || strings.HasPrefix(host, "s3-")
+}
+
+// s3ObjectKey normalizes a destination path into an S3 object key
+func s3ObjectKey(destinationPath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(destinationPath), "/")
+}
+
+// contentTypeForPath returns the MIME type for a file based on its extension
+func contentTypeForPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".mp4":
+		return "video/mp4"
+	case ".m4s":
+		return "video/iso.segment"
+	}
+	return mime.TypeByExtension(ext)
 }
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestS3Storage_ParseS3URL(t *testing.T) {
+	s3s, err := NewS3Storage(S3Config{
+		Endpoint:        "http://minio:9000",
+		UsePathStyle:    true,
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+	}, StorageConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create S3 storage: %v", err)
+	}
+
+	tests := []struct {
+		uri    string
+		bucket string
+		key    string
+	}{
+		{"s3://videos/uploads/clip.mp4", "videos", "uploads/clip.mp4"},
+		{"https://videos.s3.us-east-1.amazonaws.com/uploads/clip.mp4", "videos", "uploads/clip.mp4"},
+		{"https://videos.s3-eu-west-1.amazonaws.com/clip.mp4", "videos", "clip.mp4"},
+		{"https://s3.us-east-1.amazonaws.com/videos/uploads/clip.mp4", "videos", "uploads/clip.mp4"},
+		{"http://minio:9000/videos/uploads/my%20clip.mp4", "videos", "uploads/my clip.mp4"},
+	}
+
+	for _, test := range tests {
+		bucket, key, err := s3s.parseS3URL(test.uri)
+		if err != nil {
+			t.Errorf("parseS3URL(%s) returned error: %v", test.uri, err)
+			continue
+		}
+		if bucket != test.bucket || key != test.key {
+			t.Errorf("parseS3URL(%s) = (%s, %s), expected (%s, %s)", test.uri, bucket, key, test.bucket, test.key)
+		}
+	}
+
+	for _, uri := range []string{"s3://videos", "ftp://videos/clip.mp4", "http://minio:9000/videos"} {
+		if _, _, err := s3s.parseS3URL(uri); err == nil {
+			t.Errorf("parseS3URL(%s) expected error", uri)
+		}
+	}
+}
+
+// fakeS3 is an in-memory S3 endpoint serving path-style requests for one
+// bucket: object GET and PUT, ListObjectsV2 and multipart uploads
+type fakeS3 struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	parts      map[string]map[int][]byte // uploadID -> part number -> data
+	multiparts int                       // Completed multipart uploads
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte), parts: make(map[string]map[int][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && query.Get("list-type") == "2":
+		var keys []string
+		for objectKey := range f.objects {
+			if strings.HasPrefix(objectKey, query.Get("prefix")) {
+				keys = append(keys, objectKey)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated>`)
+		for _, objectKey := range keys {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, objectKey, len(f.objects[objectKey]))
+		}
+		fmt.Fprintf(w, `<KeyCount>%d</KeyCount></ListBucketResult>`, len(keys))
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		if key == "truncated.mp4" {
+			// Promise more than is sent so the transfer fails midway
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)*2))
+		}
+		w.Write(data)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID := fmt.Sprintf("upload-%d", len(f.parts)+1)
+		f.parts[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, key, uploadID)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.parts[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.parts[query.Get("uploadId")]
+		var data []byte
+		for number := 1; number <= len(parts); number++ {
+			data = append(data, parts[number]...)
+		}
+		f.objects[key] = data
+		f.multiparts++
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%s</Key><ETag>"done"</ETag></CompleteMultipartUploadResult>`, key)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		w.Header().Set("ETag", `"object"`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Storage_Client(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	s3s, err := NewS3Storage(S3Config{
+		Bucket:          "videos",
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+	}, StorageConfig{TempDir: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create S3 storage: %v", err)
+	}
+	ctx := context.Background()
+
+	// A small file is a single PUT, one above the part size a multipart upload
+	small := []byte("#EXTM3U\n")
+	large := bytes.Repeat([]byte("0123456789abcdef"), int(manager.DefaultUploadPartSize+1024)/16)
+	for name, data := range map[string][]byte{"master.m3u8": small, "720p.mp4": large} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s3s.UploadFile(ctx, filepath.Join(tempDir, name), "/video-1/hls/"+name); err != nil {
+			t.Fatalf("Failed to upload %s: %v", name, err)
+		}
+	}
+	if !bytes.Equal(fake.objects["video-1/hls/master.m3u8"], small) || !bytes.Equal(fake.objects["video-1/hls/720p.mp4"], large) {
+		t.Error("Expected uploaded objects to match their files")
+	}
+	if fake.multiparts != 1 {
+		t.Errorf("Expected one multipart upload, got %d", fake.multiparts)
+	}
+
+	files, err := s3s.ListFiles(ctx, "video-1/")
+	if err != nil || strings.Join(files, ",") != "video-1/hls/720p.mp4,video-1/hls/master.m3u8" {
+		t.Errorf("Unexpected listing %v (%v)", files, err)
+	}
+
+	path, err := s3s.DownloadFile(ctx, "s3://videos/video-1/hls/master.m3u8", "job-1", "source")
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	if data, _ := os.ReadFile(path); path != filepath.Join(tempDir, "job-1", "source.m3u8") || !bytes.Equal(data, small) {
+		t.Errorf("Unexpected download %s: %q", path, data)
+	}
+
+	// A failed transfer leaves no partial source behind
+	fake.objects["truncated.mp4"] = []byte("partial")
+	if _, err := s3s.DownloadFile(ctx, "s3://videos/truncated.mp4", "job-2", "source"); err == nil {
+		t.Error("Expected an error for a truncated download")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "job-2", "source.mp4")); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial download to be removed, got %v", err)
+	}
+
+	var statusErr *StatusError
+	if _, err := s3s.DownloadFile(ctx, "s3://videos/missing.mp4", "job-3", "source"); !errors.As(err, &statusErr) ||
+		statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 status error, got %v", err)
+	}
+
+	url, err := s3s.GetFileURL("video-1/hls/master.m3u8")
+	if err != nil || !strings.HasPrefix(url, server.URL+"/videos/video-1/hls/master.m3u8?") ||
+		!strings.Contains(url, "X-Amz-Signature=") {
+		t.Fatalf("Unexpected presigned URL %s (%v)", url, err)
+	}
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if data, _ := io.ReadAll(response.Body); !bytes.Equal(data, small) {
+		t.Errorf("Expected the presigned URL to serve the object, got %q", data)
+	}
+}
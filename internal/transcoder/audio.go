@@ -0,0 +1,227 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// AudioTracksAll selects every audio stream of the source
+const AudioTracksAll = "all"
+
+// audioNameUnsafe matches the characters a rendition name cannot carry
+var audioNameUnsafe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// audioTrack is a source audio stream selected for an output
+type audioTrack struct {
+	index     int    // Position among the source's audio streams, as in 0:a:N
+	language  string // ISO 639-2 language tag, "und" if untagged
+	name      string // Rendition name: audio_<language>, with the index for duplicate languages
+	label     string // Display name: the stream title, else its language
+	isDefault bool
+}
+
+// sourceAudioStreams returns the audio streams of the source. A source
+// probed without stream details counts as one untagged stream if it has an
+// audio codec.
+func sourceAudioStreams(inputInfo *VideoInfo) []AudioStream {
+	if len(inputInfo.AudioStreams) == 0 && inputInfo.AudioCodec != "" {
+		return []AudioStream{{Codec: inputInfo.AudioCodec}}
+	}
+	return inputInfo.AudioStreams
+}
+
+// selectAudioTracks returns the source audio streams an output carries. An
+// "all" entry in the output's audio_tracks selects every stream, language
+// tags select the first stream of each language in the listed order, and
+// without audio_tracks the first stream is selected. If no stream matches,
+// the first stream is used. The default track is the first selected stream
+// marked default in the source, else the first selected.
+func selectAudioTracks(output *config.OutputConfig, inputInfo *VideoInfo) []audioTrack {
+	streams := sourceAudioStreams(inputInfo)
+	if len(streams) == 0 {
+		return nil
+	}
+
+	var indexes []int
+	for _, selection := range output.AudioTracks {
+		if strings.EqualFold(selection, AudioTracksAll) {
+			indexes = indexes[:0]
+			for i := range streams {
+				indexes = append(indexes, i)
+			}
+			break
+		}
+		for i, stream := range streams {
+			if strings.EqualFold(stream.Language, selection) {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		if len(output.AudioTracks) > 0 {
+			slog.Warn("No source audio track matches the output's audio tracks, using the first",
+				"outputName", output.Name,
+				"audioTracks", output.AudioTracks,
+			)
+		}
+		indexes = []int{0}
+	}
+
+	tracks := make([]audioTrack, 0, len(indexes))
+	seen := make(map[string]bool)
+	defaultTrack := -1
+	for _, i := range indexes {
+		stream := streams[i]
+		language := strings.ToLower(stream.Language)
+		if language == "" {
+			language = "und"
+		}
+
+		name := "audio_" + audioNameUnsafe.ReplaceAllString(language, "-")
+		if seen[name] {
+			name += "_" + strconv.Itoa(i)
+		}
+		seen[name] = true
+
+		label := strings.ReplaceAll(stream.Title, `"`, "'")
+		if label == "" {
+			label = language
+		}
+
+		if stream.Default && defaultTrack < 0 {
+			defaultTrack = len(tracks)
+		}
+		tracks = append(tracks, audioTrack{index: i, language: language, name: name, label: label})
+	}
+	tracks[max(defaultTrack, 0)].isDefault = true
+
+	return tracks
+}
+
+// audioMapArgs returns the options that map the tracks to the output's
+// audio streams, numbered from first, and tag their language
+func audioMapArgs(tracks []audioTrack, first int) []string {
+	var args []string
+	for i, track := range tracks {
+		args = append(args,
+			"-map", fmt.Sprintf("0:a:%d", track.index),
+			fmt.Sprintf("-metadata:s:a:%d", first+i), "language="+track.language,
+		)
+	}
+	return args
+}
+
+// audioTrackNames returns the rendition names of audio tracks for output
+// metadata
+func audioTrackNames(tracks []audioTrack) string {
+	names := make([]string, len(tracks))
+	for i, track := range tracks {
+		names[i] = track.name
+	}
+	return strings.Join(names, ",")
+}
+
+// ladderAudioBitrate returns the bitrate of audio encoded once for a whole
+// ladder: the highest audio bitrate of its profiles
+func ladderAudioBitrate(ladder []config.ProfileConfig) int {
+	bitrate := 0
+	for _, profile := range ladder {
+		bitrate = max(bitrate, profile.AudioBitrateKbps)
+	}
+	if bitrate <= 0 {
+		return 128
+	}
+	return bitrate
+}
+
+// hlsAudio describes how an HLS output carries audio. A single track is
+// muxed into every video rendition. Several tracks, or an audio-only
+// rendition, need separate audio renditions that the master playlist lists
+// in an EXT-X-MEDIA group, with video renditions carrying no audio.
+type hlsAudio struct {
+	tracks    []audioTrack
+	separate  bool
+	audioOnly bool
+}
+
+// hlsAudioFor returns the audio layout of an HLS output
+func hlsAudioFor(output *config.OutputConfig, inputInfo *VideoInfo) hlsAudio {
+	tracks := selectAudioTracks(output, inputInfo)
+	return hlsAudio{
+		tracks:    tracks,
+		separate:  len(tracks) > 1 || (output.AudioOnly && len(tracks) > 0),
+		audioOnly: output.AudioOnly && len(tracks) > 0,
+	}
+}
+
+// muxedTrack returns the track muxed into video renditions, or nil
+func (a hlsAudio) muxedTrack() *audioTrack {
+	if a.separate || len(a.tracks) == 0 {
+		return nil
+	}
+	return &a.tracks[0]
+}
+
+// renditions returns the separate audio renditions
+func (a hlsAudio) renditions() []audioTrack {
+	if !a.separate {
+		return nil
+	}
+	return a.tracks
+}
+
+// transcodeHLSAudio encodes a separate audio rendition of an HLS output
+// into its own directory, laid out like a video rendition
+func (t *Transcoder) transcodeHLSAudio(ctx context.Context, inputPath string, track audioTrack,
+	bitrateKbps int, outputDir string, output *config.OutputConfig,
+	progressCallback ProgressCallback) ([]models.OutputFile, error) {
+
+	trackDir := filepath.Join(outputDir, track.name)
+	if err := os.MkdirAll(trackDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audio rendition directory: %w", err)
+	}
+
+	args := t.buildHLSAudioArgs(inputPath, trackDir, track, bitrateKbps, output)
+
+	slog.Debug("Running FFmpeg for HLS audio",
+		"track", track.name,
+		"args", strings.Join(args, " "),
+	)
+
+	if err := t.runFFmpegWithProgress(ctx, args, 0, progressCallback); err != nil {
+		return nil, fmt.Errorf("ffmpeg execution failed: %w", err)
+	}
+
+	return t.collectHLSProfileFiles(trackDir, track.name, hlsSegmentsFor(output))
+}
+
+// buildHLSAudioArgs builds FFmpeg arguments for a separate HLS audio
+// rendition. The job's extra args are meant for video encodes and are
+// left out.
+func (t *Transcoder) buildHLSAudioArgs(inputPath, outputDir string, track audioTrack,
+	bitrateKbps int, output *config.OutputConfig) []string {
+
+	args := []string{"-i", inputPath, "-vn"}
+	args = append(args, audioMapArgs([]audioTrack{track}, 0)...)
+	args = append(args,
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", bitrateKbps),
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentLength(output)),
+		"-hls_list_size", "0",
+		"-hls_flags", "independent_segments",
+	)
+	args = append(args, hlsSegmentsFor(output).args(outputDir, track.name)...)
+
+	return append(args, filepath.Join(outputDir, track.name+".m3u8"))
+}
@@ -0,0 +1,132 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestSelectAudioTracks(t *testing.T) {
+	inputInfo := &VideoInfo{AudioCodec: "aac", AudioStreams: []AudioStream{
+		{Codec: "aac", Language: "eng", Title: "English"},
+		{Codec: "aac", Language: "spa", Default: true},
+		{Codec: "ac3", Language: "eng", Title: "Commentary"},
+		{Codec: "aac"},
+	}}
+
+	tests := []struct {
+		name        string
+		audioTracks []string
+		expected    string // name:index:label, the default marked with *
+	}{
+		{"first by default", nil, "audio_eng:0:English"},
+		{"by language", []string{"spa", "ENG"}, "*audio_spa:1:spa audio_eng:0:English"},
+		{"all", []string{"all"}, "audio_eng:0:English *audio_spa:1:spa audio_eng_2:2:Commentary audio_und:3:und"},
+		{"no match", []string{"jpn"}, "audio_eng:0:English"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracks := selectAudioTracks(&config.OutputConfig{AudioTracks: test.audioTracks}, inputInfo)
+
+			var got []string
+			for _, track := range tracks {
+				entry := fmt.Sprintf("%s:%d:%s", track.name, track.index, track.label)
+				if track.isDefault {
+					entry = "*" + entry
+				}
+				got = append(got, entry)
+			}
+			// A single selected track is the default
+			expected := test.expected
+			if len(tracks) == 1 {
+				expected = "*" + expected
+			}
+			if strings.Join(got, " ") != expected {
+				t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+			}
+		})
+	}
+
+	// Sources probed without stream details still carry their audio
+	if tracks := selectAudioTracks(&config.OutputConfig{}, &VideoInfo{AudioCodec: "aac"}); len(tracks) != 1 {
+		t.Errorf("Expected one untagged track, got %v", tracks)
+	}
+	if tracks := selectAudioTracks(&config.OutputConfig{AudioTracks: []string{"all"}}, &VideoInfo{}); tracks != nil {
+		t.Errorf("Expected no tracks for a silent source, got %v", tracks)
+	}
+}
+
+func TestCreateMasterPlaylist_AudioGroup(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, AudioBitrateKbps: 128},
+	}
+	inputInfo := &VideoInfo{FrameRate: 30, AudioCodec: "aac", AudioStreams: []AudioStream{
+		{Codec: "aac", Language: "eng", Title: "English"},
+		{Codec: "aac", Language: "spa"},
+	}}
+	output := &config.OutputConfig{Package: "hls", AudioTracks: []string{"all"}, AudioOnly: true}
+
+	playlist, err := transcoder.createMasterPlaylist(ladder, inputInfo, hlsAudioFor(output, inputInfo), nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create master playlist: %v", err)
+	}
+	for _, expected := range []string{
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="English",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,URI="audio_eng/audio_eng.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="spa",LANGUAGE="spa",DEFAULT=NO,AUTOSELECT=YES,URI="audio_spa/audio_spa.m3u8"`,
+		`#EXT-X-STREAM-INF:BANDWIDTH=928000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2",AUDIO="audio",NAME="360p"`,
+		"#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS=\"mp4a.40.2\",AUDIO=\"audio\",NAME=\"audio\"\naudio_eng/audio_eng.m3u8",
+	} {
+		if !strings.Contains(playlist, expected) {
+			t.Errorf("Expected %s in master playlist, got:\n%s", expected, playlist)
+		}
+	}
+
+	// A single muxed track needs no audio group
+	playlist, _ = transcoder.createMasterPlaylist(ladder, inputInfo, hlsAudioFor(&config.OutputConfig{}, inputInfo), nil, nil)
+	if strings.Contains(playlist, "EXT-X-MEDIA") || strings.Contains(playlist, "AUDIO=") {
+		t.Errorf("Expected muxed audio without an audio group, got:\n%s", playlist)
+	}
+}
+
+func TestBuildHLSArgs_SeparateAudio(t *testing.T) {
+	transcoder := &Transcoder{}
+	profile := config.ProfileConfig{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, Fit: FitSource}
+	inputInfo := &VideoInfo{Width: 1920, Height: 1080, AudioCodec: "aac", AudioStreams: []AudioStream{
+		{Codec: "aac", Language: "eng"}, {Codec: "aac", Language: "fre"},
+	}}
+	output := &config.OutputConfig{Name: "hls", Package: "hls", AudioTracks: []string{"fre"}}
+
+	// A single selected track is muxed into the video rendition
+	args := strings.Join(transcoder.buildHLSFFmpegArgs("in.mp4", "/out/720p", &profile, inputInfo, output,
+		config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-map 0:a:1 -metadata:s:a:0 language=fre -c:a aac -b:a 128k") {
+		t.Errorf("Expected the French track muxed in, got %s", args)
+	}
+
+	// Separate renditions leave the video without audio
+	output.AudioTracks = []string{"all"}
+	args = strings.Join(transcoder.buildHLSFFmpegArgs("in.mp4", "/out/720p", &profile, inputInfo, output,
+		config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-map 0:v:0") || !strings.Contains(args, " -an ") || strings.Contains(args, "0:a:") {
+		t.Errorf("Expected video-only rendition, got %s", args)
+	}
+
+	track := hlsAudioFor(output, inputInfo).renditions()[1]
+	args = strings.Join(transcoder.buildHLSAudioArgs("in.mp4", "/out/audio_fre", track, 128, output), " ")
+	if !strings.Contains(args, "-vn -map 0:a:1 -metadata:s:a:0 language=fre -c:a aac -b:a 128k -f hls") ||
+		!strings.HasSuffix(args, "/out/audio_fre/audio_fre.m3u8") {
+		t.Errorf("Expected French audio rendition, got %s", args)
+	}
+
+	// Single-pass encodes add the audio renditions as variant streams
+	args = strings.Join(transcoder.buildHLSSinglePassArgs("in.mp4", "/out", []config.ProfileConfig{profile},
+		inputInfo, output, config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-var_stream_map v:0,name:720p a:0,name:audio_eng a:1,name:audio_fre") {
+		t.Errorf("Expected audio variant streams, got %s", args)
+	}
+}
@@ -0,0 +1,415 @@
+package transcoder
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+// videoCodec describes a video encoder, how to drive it and which
+// containers can carry its output
+type videoCodec struct {
+	name       string          // Codec family: h264, hevc, vp9 or av1
+	encoder    string          // ffmpeg encoder
+	containers map[string]bool // See outputContainer
+	levels     []codecLevel    // Ascending
+	defaultCRF int             // Quality of CRF encodes without a crf setting
+	maxCRF     int             // Highest (worst) CRF the encoder accepts
+}
+
+// codecLevel is a codec level with the largest picture, in luma samples,
+// and luma sample rate it allows
+type codecLevel struct {
+	name       string
+	idc        int // Level number as coded in the bitstream and codec strings
+	maxPicture int
+	maxRate    float64
+}
+
+var (
+	h264Codec = videoCodec{
+		name:       "h264",
+		encoder:    "libx264",
+		containers: containerSet("mp4", "mov", "mkv", "avi", "hls-ts", "hls-fmp4", "dash"),
+		levels: []codecLevel{
+			{"3.0", 30, 414720, 10368000},
+			{"3.1", 31, 921600, 27648000},
+			{"3.2", 32, 1310720, 55296000},
+			{"4.0", 40, 2097152, 62914560},
+			{"4.2", 42, 2228224, 133693440},
+			{"5.0", 50, 5652480, 150994944},
+			{"5.1", 51, 9437184, 251658240},
+			{"5.2", 52, 9437184, 530841600},
+			{"6.0", 60, 35651584, 1069547520},
+			{"6.1", 61, 35651584, 2139095040},
+			{"6.2", 62, 35651584, 4278190080},
+		},
+		defaultCRF: 23,
+		maxCRF:     51,
+	}
+	hevcCodec = videoCodec{
+		name:       "hevc",
+		encoder:    "libx265",
+		containers: containerSet("mp4", "mov", "mkv", "hls-fmp4", "dash"),
+		levels: []codecLevel{
+			{"3.0", 90, 552960, 16588800},
+			{"3.1", 93, 983040, 33177600},
+			{"4.0", 120, 2228224, 66846720},
+			{"4.1", 123, 2228224, 133693440},
+			{"5.0", 150, 8912896, 267386880},
+			{"5.1", 153, 8912896, 534773760},
+			{"5.2", 156, 8912896, 1069547520},
+			{"6.0", 180, 35651584, 1069547520},
+			{"6.1", 183, 35651584, 2139095040},
+			{"6.2", 186, 35651584, 4278190080},
+		},
+		defaultCRF: 28,
+		maxCRF:     51,
+	}
+	vp9Codec = videoCodec{
+		name:       "vp9",
+		encoder:    "libvpx-vp9",
+		containers: containerSet("mp4", "mkv", "webm", "dash"),
+		levels: []codecLevel{
+			{"3.0", 30, 552960, 20736000},
+			{"3.1", 31, 983040, 36864000},
+			{"4.0", 40, 2228224, 83558400},
+			{"4.1", 41, 2228224, 160432128},
+			{"5.0", 50, 8912896, 311951360},
+			{"5.1", 51, 8912896, 588251136},
+			{"5.2", 52, 8912896, 1176502272},
+			{"6.0", 60, 35651584, 1176502272},
+			{"6.1", 61, 35651584, 2353004544},
+			{"6.2", 62, 35651584, 4706009088},
+		},
+		defaultCRF: 31,
+		maxCRF:     63,
+	}
+	av1Levels = []codecLevel{
+		{"2.0", 0, 147456, 4423680},
+		{"2.1", 1, 278784, 8363520},
+		{"3.0", 4, 665856, 19975680},
+		{"3.1", 5, 1065024, 31950720},
+		{"4.0", 8, 2359296, 70778880},
+		{"4.1", 9, 2359296, 141557760},
+		{"5.0", 12, 8912896, 267386880},
+		{"5.1", 13, 8912896, 534773760},
+		{"5.2", 14, 8912896, 1069547520},
+		{"6.0", 16, 35651584, 1069547520},
+		{"6.1", 17, 35651584, 2139095040},
+		{"6.2", 18, 35651584, 4278190080},
+	}
+	svtAV1Codec = videoCodec{
+		name:       "av1",
+		encoder:    "libsvtav1",
+		containers: containerSet("mp4", "mkv", "webm", "hls-fmp4", "dash"),
+		levels:     av1Levels,
+		defaultCRF: 35,
+		maxCRF:     63,
+	}
+	aomAV1Codec = videoCodec{
+		name:       "av1",
+		encoder:    "libaom-av1",
+		containers: svtAV1Codec.containers,
+		levels:     av1Levels,
+		defaultCRF: 30,
+		maxCRF:     63,
+	}
+)
+
+// videoCodecs maps the codec names a profile may use to their codec
+var videoCodecs = map[string]*videoCodec{
+	"h264":       &h264Codec,
+	"libx264":    &h264Codec,
+	"hevc":       &hevcCodec,
+	"h265":       &hevcCodec,
+	"libx265":    &hevcCodec,
+	"vp9":        &vp9Codec,
+	"libvpx-vp9": &vp9Codec,
+	"av1":        &svtAV1Codec,
+	"libsvtav1":  &svtAV1Codec,
+	"libaom-av1": &aomAV1Codec,
+}
+
+// presetSpeeds are the x264 presets from fastest to slowest. Encoders with
+// numeric speed settings get the equivalent of a named preset.
+var presetSpeeds = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// containerSet builds a set of container names
+func containerSet(containers ...string) map[string]bool {
+	set := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		set[container] = true
+	}
+	return set
+}
+
+// lookupVideoCodec returns the codec of a profile codec setting; empty
+// selects H.264
+func lookupVideoCodec(name string) (*videoCodec, error) {
+	if name == "" {
+		return &h264Codec, nil
+	}
+	codec, exists := videoCodecs[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported video codec: %s", name)
+	}
+	return codec, nil
+}
+
+// profileCodec returns the codec of a resolved profile, which was checked
+// when the transcoder was created
+func profileCodec(profile *config.ProfileConfig) *videoCodec {
+	codec, err := lookupVideoCodec(profile.Codec)
+	if err != nil {
+		return &h264Codec
+	}
+	return codec
+}
+
+// outputContainer names what carries an output's video for codec
+// compatibility: "hls-ts", "hls-fmp4", "dash" or the progressive container
+func outputContainer(output *config.OutputConfig) string {
+	switch strings.ToLower(output.Package) {
+	case "hls":
+		return "hls-" + hlsSegmentsFor(output).container()
+	case "dash":
+		return "dash"
+	default:
+		return progressiveContainer(output)
+	}
+}
+
+// progressiveContainer returns the file container of a progressive output
+func progressiveContainer(output *config.OutputConfig) string {
+	if output.Container == "" {
+		return "mp4"
+	}
+	return strings.ToLower(output.Container)
+}
+
+// validateOutput checks that the codec of each profile of an output is
+// supported, can be carried by the output's container and supports the
+// profile's rate control
+func validateOutput(output *config.OutputConfig) error {
+	container := outputContainer(output)
+
+	profiles := output.Profiles
+	if len(profiles) == 0 {
+		profiles = []config.ProfileConfig{{Name: output.Profile}}
+	}
+	for _, profile := range profiles {
+		codecName := profile.Codec
+		if codecName == "" {
+			codecName = output.Codec
+		}
+		codec, err := lookupVideoCodec(codecName)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", profile.Name, err)
+		}
+		if !codec.containers[container] {
+			return fmt.Errorf("profile %s: %s video cannot be packaged as %s", profile.Name, codec.name, container)
+		}
+		if err := validateRateControl(codec, &profile); err != nil {
+			return fmt.Errorf("profile %s: %w", profile.Name, err)
+		}
+	}
+	return nil
+}
+
+// level returns the lowest level of the codec that allows the frame size
+// and rate, or the highest level if none does
+func (c *videoCodec) level(width, height int, frameRate float64) codecLevel {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	if c.name == "h264" {
+		// H.264 limits are in 16x16 macroblocks
+		width, height = (width+15)/16*16, (height+15)/16*16
+	}
+
+	picture := width * height
+	for _, level := range c.levels {
+		if picture <= level.maxPicture && float64(picture)*frameRate <= level.maxRate {
+			return level
+		}
+	}
+	return c.levels[len(c.levels)-1]
+}
+
+// codecsTag returns the RFC 6381 codec string of 8-bit 4:2:0 video encoded
+// with the options of encodeArgs
+func (c *videoCodec) codecsTag(level codecLevel, streaming bool) string {
+	switch c.name {
+	case "hevc":
+		return fmt.Sprintf("hvc1.1.6.L%d.B0", level.idc)
+	case "vp9":
+		return fmt.Sprintf("vp09.00.%02d.08", level.idc)
+	case "av1":
+		return fmt.Sprintf("av01.0.%02dM.08", level.idc)
+	default:
+		if streaming {
+			return fmt.Sprintf("avc1.4d40%02x", level.idc) // Main
+		}
+		return fmt.Sprintf("avc1.6400%02x", level.idc) // High
+	}
+}
+
+// streamArgs collects the options of one video stream. stream is a stream
+// specifier suffix such as ":0", or empty when the output has one video
+// stream.
+type streamArgs struct {
+	stream     string
+	args       []string
+	x264Params []string // Joined into one -x264-params option
+	x265Params []string // Joined into one -x265-params option
+}
+
+// add appends a video option for the stream
+func (s *streamArgs) add(option, value string) {
+	s.args = append(s.args, option+":v"+s.stream, value)
+}
+
+// list returns the stream's options
+func (s *streamArgs) list() []string {
+	args := s.args
+	if len(s.x264Params) > 0 {
+		args = append(args, "-x264-params:v"+s.stream, strings.Join(s.x264Params, ":"))
+	}
+	if len(s.x265Params) > 0 {
+		args = append(args, "-x265-params:v"+s.stream, strings.Join(s.x265Params, ":"))
+	}
+	return args
+}
+
+// encodeArgs adds the encoder, profile, level and speed options of a video
+// stream. Streaming outputs use the H.264 Main profile, which more players
+// decode than High.
+func (c *videoCodec) encodeArgs(s *streamArgs, level codecLevel, streaming bool, preset string) {
+	s.add("-c", c.encoder)
+	s.add("-pix_fmt", "yuv420p")
+
+	speed := -1
+	for i, name := range presetSpeeds {
+		if strings.EqualFold(preset, name) {
+			speed = i
+		}
+	}
+
+	switch c.encoder {
+	case "libx264":
+		h264Profile := "high"
+		if streaming {
+			h264Profile = "main"
+		}
+		s.add("-profile", h264Profile)
+		s.add("-level", level.name)
+		if preset != "" {
+			s.add("-preset", preset)
+		}
+	case "libx265":
+		// Apple players require the hvc1 sample entry
+		s.add("-profile", "main")
+		s.add("-tag", "hvc1")
+		s.x265Params = append(s.x265Params, "level-idc="+level.name)
+		if preset != "" {
+			s.add("-preset", preset)
+		}
+	case "libvpx-vp9":
+		s.add("-profile", "0")
+		s.add("-row-mt", "1")
+		s.add("-deadline", "good")
+		if speed >= 0 {
+			s.add("-cpu-used", strconv.Itoa(5-speed*5/8))
+		} else if preset != "" {
+			s.add("-cpu-used", preset)
+		}
+	case "libsvtav1":
+		if speed >= 0 {
+			s.add("-preset", strconv.Itoa(12-speed))
+		} else if preset != "" {
+			s.add("-preset", preset)
+		}
+	case "libaom-av1":
+		s.add("-row-mt", "1")
+		if speed >= 0 {
+			s.add("-cpu-used", strconv.Itoa(8-speed))
+		} else if preset != "" {
+			s.add("-cpu-used", preset)
+		}
+	}
+}
+
+// keyframeArgs adds the options that start every segment of a streaming
+// output with a keyframe, so renditions switch at the same frames. A
+// keyframe is forced at each segment boundary; with a known frame rate the
+// GOP is also fixed to one segment of frames, so encoders place no other
+// keyframes. Scene cut keyframes are disabled and HEVC GOPs closed, as
+// segments must decode on their own.
+func (c *videoCodec) keyframeArgs(s *streamArgs, frameRate float64, segmentLength int) {
+	s.add("-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentLength))
+
+	if gop := segmentGOP(frameRate, segmentLength); gop > 0 {
+		s.add("-g", strconv.Itoa(gop))
+		s.add("-keyint_min", strconv.Itoa(gop))
+	}
+
+	switch c.encoder {
+	case "libx264":
+		s.add("-sc_threshold", "0")
+	case "libx265":
+		s.add("-forced-idr", "1")
+		s.x265Params = append(s.x265Params, "scenecut=0", "open-gop=0")
+	}
+}
+
+// segmentGOP returns the number of frames in a segment of segmentLength
+// seconds, or 0 if the frame rate is unknown
+func segmentGOP(frameRate float64, segmentLength int) int {
+	if frameRate <= 0 || segmentLength <= 0 {
+		return 0
+	}
+	return max(int(math.Round(frameRate*float64(segmentLength))), 1)
+}
+
+// videoStreamArgs returns the encoding options of a rendition's video
+// stream in an encode pass; see streamArgs for stream. segmentLength is the
+// segment length in seconds of a streaming output, which aligns keyframes
+// to segments, or 0 for progressive files.
+func videoStreamArgs(profile *config.ProfileConfig, inputInfo *VideoInfo, stream string,
+	segmentLength int, preset string, pass encodePass) []string {
+
+	codec := profileCodec(profile)
+	level := codec.level(profile.Width, profile.Height, inputInfo.FrameRate)
+
+	s := &streamArgs{stream: stream}
+	codec.encodeArgs(s, level, segmentLength > 0, preset)
+	if segmentLength > 0 {
+		codec.keyframeArgs(s, inputInfo.FrameRate, segmentLength)
+	}
+	codec.rateArgs(s, profile, pass)
+	return s.list()
+}
+
+// audioCodecFor returns the audio encoder for a container and its codec
+// string: Opus for WebM, which cannot carry AAC, and AAC otherwise
+func audioCodecFor(container string) (string, string) {
+	if container == "webm" {
+		return "libopus", "opus"
+	}
+	return "aac", "mp4a.40.2"
+}
+
+// renditionCodecs returns the CODECS attribute value of a rendition
+func renditionCodecs(profile *config.ProfileConfig, inputInfo *VideoInfo, container string, streaming bool) string {
+	codec := profileCodec(profile)
+	codecs := codec.codecsTag(codec.level(profile.Width, profile.Height, inputInfo.FrameRate), streaming)
+	if inputInfo.AudioCodec != "" {
+		_, audioTag := audioCodecFor(container)
+		codecs += "," + audioTag
+	}
+	return codecs
+}
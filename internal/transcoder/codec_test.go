@@ -0,0 +1,186 @@
+package transcoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestValidateOutput_Codecs(t *testing.T) {
+	tests := []struct {
+		name   string
+		output config.OutputConfig
+		valid  bool
+	}{
+		{"h264 mp4 by default", config.OutputConfig{Package: "progressive", Profile: "720p"}, true},
+		{"h264 in webm", config.OutputConfig{Package: "progressive", Container: "webm", Profile: "720p"}, false},
+		{"vp9 in webm", config.OutputConfig{Package: "progressive", Container: "webm", Codec: "vp9", Profile: "720p"}, true},
+		{"av1 in webm", config.OutputConfig{Package: "progressive", Container: "webm", Codec: "libaom-av1", Profile: "720p"}, true},
+		{"vp9 in mov", config.OutputConfig{Package: "progressive", Container: "mov", Codec: "vp9", Profile: "720p"}, false},
+		{"hevc in hls ts", config.OutputConfig{Package: "hls", Codec: "hevc", Profile: "720p"}, false},
+		{"hevc in hls fmp4", config.OutputConfig{Package: "hls", Container: "fmp4", Codec: "h265", Profile: "720p"}, true},
+		{"vp9 in hls fmp4", config.OutputConfig{Package: "hls", Container: "fmp4", Codec: "vp9", Profile: "720p"}, false},
+		{"vp9 in dash", config.OutputConfig{Package: "dash", Codec: "vp9", Profile: "720p"}, true},
+		{"unknown codec", config.OutputConfig{Package: "dash", Codec: "mpeg2", Profile: "720p"}, false},
+		{
+			"profile codec overrides output codec",
+			config.OutputConfig{Package: "progressive", Container: "webm", Codec: "vp9", Profiles: []config.ProfileConfig{
+				{Name: "360p"}, {Name: "720p", Codec: "h264"},
+			}},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateOutput(&test.output)
+			if test.valid && err != nil {
+				t.Errorf("Expected valid output, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestCodecsTag(t *testing.T) {
+	tests := []struct {
+		codec     string
+		width     int
+		height    int
+		frameRate float64
+		streaming bool
+		expected  string
+	}{
+		{"h264", 1280, 720, 30, true, "avc1.4d401f"},
+		{"h264", 1920, 1080, 30, true, "avc1.4d4028"},
+		{"h264", 1920, 1080, 60, false, "avc1.64002a"},
+		{"h264", 640, 360, 0, true, "avc1.4d401e"},
+		{"hevc", 1920, 1080, 30, true, "hvc1.1.6.L120.B0"},
+		{"hevc", 3840, 2160, 30, true, "hvc1.1.6.L150.B0"},
+		{"vp9", 1280, 720, 30, true, "vp09.00.31.08"},
+		{"av1", 1920, 1080, 30, true, "av01.0.08M.08"},
+		{"av1", 3840, 2160, 60, true, "av01.0.13M.08"},
+	}
+
+	for _, test := range tests {
+		codec, err := lookupVideoCodec(test.codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		level := codec.level(test.width, test.height, test.frameRate)
+		if got := codec.codecsTag(level, test.streaming); got != test.expected {
+			t.Errorf("codecsTag(%s %dx%d@%v) = %s, expected %s",
+				test.codec, test.width, test.height, test.frameRate, got, test.expected)
+		}
+	}
+}
+
+func TestVideoStreamArgs(t *testing.T) {
+	inputInfo := &VideoInfo{FrameRate: 30}
+	profile := config.ProfileConfig{Width: 1920, Height: 1080, VideoBitrateKbps: 5000}
+
+	tests := []struct {
+		codec    string
+		preset   string
+		expected string
+	}{
+		{"", "fast", "-c:v:0 libx264 -pix_fmt:v:0 yuv420p -profile:v:0 main -level:v:0 4.0 -preset:v:0 fast"},
+		{"hevc", "", "-tag:v:0 hvc1"},
+		{"hevc", "", "-x265-params:v:0 level-idc=4.0:scenecut=0:open-gop=0"},
+		{"vp9", "medium", "-c:v:0 libvpx-vp9 -pix_fmt:v:0 yuv420p -profile:v:0 0 -row-mt:v:0 1 -deadline:v:0 good -cpu-used:v:0 2"},
+		{"av1", "veryslow", "-c:v:0 libsvtav1 -pix_fmt:v:0 yuv420p -preset:v:0 4"},
+		{"av1", "8", "-preset:v:0 8"},
+		{"libaom-av1", "ultrafast", "-c:v:0 libaom-av1 -pix_fmt:v:0 yuv420p -row-mt:v:0 1 -cpu-used:v:0 8"},
+	}
+
+	for _, test := range tests {
+		profile.Codec = test.codec
+		args := strings.Join(videoStreamArgs(&profile, inputInfo, ":0", 6, test.preset, encodePass{}), " ")
+		if !strings.Contains(args, test.expected) {
+			t.Errorf("Expected %q for codec %q, got %s", test.expected, test.codec, args)
+		}
+	}
+
+	// SVT-AV1 takes a target bitrate only
+	profile.Codec = "av1"
+	if args := strings.Join(videoStreamArgs(&profile, inputInfo, "", 6, "", encodePass{}), " "); strings.Contains(args, "maxrate") {
+		t.Errorf("Expected no maxrate for SVT-AV1, got %s", args)
+	}
+}
+
+func TestKeyframeArgs(t *testing.T) {
+	profile := config.ProfileConfig{Width: 1280, Height: 720, VideoBitrateKbps: 2500}
+
+	tests := []struct {
+		frameRate     float64
+		segmentLength int
+		expected      string
+	}{
+		{25, 4, "-force_key_frames:v expr:gte(t,n_forced*4) -g:v 100 -keyint_min:v 100 -sc_threshold:v 0"},
+		{29.97, 6, "-force_key_frames:v expr:gte(t,n_forced*6) -g:v 180 -keyint_min:v 180 -sc_threshold:v 0"},
+		{50, 6, "-g:v 300 -keyint_min:v 300"},
+		{0, 6, "-force_key_frames:v expr:gte(t,n_forced*6) -sc_threshold:v 0"},
+	}
+
+	for _, test := range tests {
+		args := strings.Join(videoStreamArgs(&profile, &VideoInfo{FrameRate: test.frameRate}, "",
+			test.segmentLength, "", encodePass{}), " ")
+		if !strings.Contains(args, test.expected) {
+			t.Errorf("Expected %q at %v fps, got %s", test.expected, test.frameRate, args)
+		}
+	}
+
+	// Progressive files keep the encoder's own keyframe placement
+	args := strings.Join(videoStreamArgs(&profile, &VideoInfo{FrameRate: 30}, "", 0, "", encodePass{}), " ")
+	if strings.Contains(args, "force_key_frames") || strings.Contains(args, "-g:v") {
+		t.Errorf("Expected no keyframe options for progressive output, got %s", args)
+	}
+}
+
+func TestBuildProgressiveFFmpegArgs_WebM(t *testing.T) {
+	transcoder := &Transcoder{}
+	profile := &config.ProfileConfig{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, Codec: "vp9", Fit: FitSource}
+
+	args := strings.Join(transcoder.buildProgressiveFFmpegArgs("in.mp4", "/out/720p.webm", "webm", profile,
+		&VideoInfo{Width: 1920, Height: 1080}, []audioTrack{{language: "und"}}, config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-c:v libvpx-vp9") || !strings.Contains(args, "-c:a libopus") {
+		t.Errorf("Expected VP9 and Opus for WebM, got %s", args)
+	}
+	if strings.Contains(args, "movflags") || strings.Contains(args, "libx264") {
+		t.Errorf("Expected no MP4 options for WebM, got %s", args)
+	}
+
+	profile.Codec = ""
+	args = strings.Join(transcoder.buildProgressiveFFmpegArgs("in.mp4", "/out/720p.mp4", "mp4", profile,
+		&VideoInfo{Width: 1920, Height: 1080}, []audioTrack{{language: "und"}}, config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-profile:v high") || !strings.Contains(args, "-movflags +faststart") {
+		t.Errorf("Expected High profile and faststart for MP4, got %s", args)
+	}
+}
+
+func TestCreateMasterPlaylist_Codecs(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, AudioBitrateKbps: 128},
+		{Name: "2160p", Width: 3840, Height: 2160, VideoBitrateKbps: 12000, AudioBitrateKbps: 128, Codec: "hevc"},
+	}
+
+	playlist, err := transcoder.createMasterPlaylist(ladder, &VideoInfo{FrameRate: 30, AudioCodec: "aac"}, hlsAudio{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create master playlist: %v", err)
+	}
+	for _, expected := range []string{`CODECS="avc1.4d401f,mp4a.40.2"`, `CODECS="hvc1.1.6.L150.B0,mp4a.40.2"`} {
+		if !strings.Contains(playlist, expected) {
+			t.Errorf("Expected %s in master playlist, got:\n%s", expected, playlist)
+		}
+	}
+
+	// Video-only sources list the video codec alone
+	playlist, _ = transcoder.createMasterPlaylist(ladder[:1], &VideoInfo{FrameRate: 30}, hlsAudio{}, nil, nil)
+	if !strings.Contains(playlist, `CODECS="avc1.4d401f"`) {
+		t.Errorf("Expected video-only codecs, got:\n%s", playlist)
+	}
+}
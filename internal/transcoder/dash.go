@@ -0,0 +1,238 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// DASH segment names; $RepresentationID$ is the index of the encoded stream
+const (
+	dashManifestName = "manifest.mpd"
+	dashInitName     = "init-$RepresentationID$.mp4"
+	dashMediaName    = "chunk-$RepresentationID$-$Number%05d$.m4s"
+)
+
+// dashSegmentPattern matches DASH init and media segment file names and
+// captures their representation ID
+var dashSegmentPattern = regexp.MustCompile(`^(?:init-(\d+)\.mp4|chunk-(\d+)-\d+\.m4s)$`)
+
+// transcodeDASH performs MPEG-DASH packaging. The ladder is encoded with one
+// ffmpeg process into fMP4 (CMAF) segments described by an MPD manifest,
+// with the video renditions in one adaptation set per codec and each
+// selected audio track in an adaptation set of its own. With HLSPlaylist set, HLS playlists that
+// reference the same segments are written too, so one set of media serves
+// both manifests.
+func (t *Transcoder) transcodeDASH(ctx context.Context, inputPath string,
+	output *config.OutputConfig, outputDir string, inputInfo *VideoInfo,
+	ffmpegConfig config.JobFFmpegConfig, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
+
+	startTime := time.Now()
+	slog.Info("Starting DASH transcoding",
+		"inputPath", inputPath,
+		"outputDir", outputDir,
+		"profiles", len(output.Profiles),
+	)
+
+	profiles := t.outputProfiles(output)
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles specified for DASH output")
+	}
+	ladder, skipped := resolveLadder(output, profiles, inputInfo)
+
+	passes := encodePasses(ladder, passLogFile(outputDir, "dash"))
+	err := t.runEncodePasses(ctx, passes, func(pass encodePass) []string {
+		args := t.buildDASHFFmpegArgs(inputPath, outputDir, ladder, inputInfo, output, ffmpegConfig, pass)
+		slog.Debug("Running FFmpeg for DASH",
+			"renditions", len(ladder),
+			"pass", pass.number,
+			"args", strings.Join(args, " "),
+		)
+		return args
+	}, inputInfo.TotalFrames, progressCallback)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg execution failed: %w", err)
+	}
+
+	tracks := selectAudioTracks(output, inputInfo)
+	files, err := t.collectDASHFiles(outputDir, ladder, tracks, output.HLSPlaylist)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ConversionOutput{
+		Name:    output.Name,
+		Type:    "dash",
+		Profile: output.Profile,
+		Files:   files,
+		Metadata: map[string]string{
+			"package":         "dash",
+			"segment_length":  strconv.Itoa(hlsSegmentLength(output)),
+			"hls_playlist":    strconv.FormatBool(output.HLSPlaylist),
+			"total_frames":    strconv.Itoa(inputInfo.TotalFrames),
+			"processing_time": time.Since(startTime).String(),
+		},
+	}
+	if len(skipped) > 0 {
+		result.Metadata["skipped_profiles"] = strings.Join(skipped, ",")
+	}
+	if len(tracks) > 0 {
+		result.Metadata["audio_tracks"] = audioTrackNames(tracks)
+	}
+
+	slog.Info("DASH transcoding completed",
+		"outputName", output.Name,
+		"fileCount", len(files),
+		"duration", time.Since(startTime),
+	)
+
+	return result, nil
+}
+
+// buildDASHFFmpegArgs builds FFmpeg arguments for one pass of DASH packaging
+func (t *Transcoder) buildDASHFFmpegArgs(inputPath, outputDir string, ladder []config.ProfileConfig,
+	inputInfo *VideoInfo, output *config.OutputConfig, ffmpegConfig config.JobFFmpegConfig, pass encodePass) []string {
+
+	args := []string{"-i", inputPath}
+
+	// Add hardware acceleration if configured
+	if ffmpegConfig.HWAccel != "" {
+		args = append([]string{"-hwaccel", ffmpegConfig.HWAccel}, args...)
+	}
+
+	args = append(args, "-filter_complex", splitFilter(ladder, inputInfo))
+	for i, profile := range ladder {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i))
+		args = append(args, videoStreamArgs(&profile, inputInfo, fmt.Sprintf(":%d", i), hlsSegmentLength(output),
+			ffmpegConfig.Preset, pass)...)
+	}
+	if pass.analysis() {
+		return append(args, analysisOutputArgs()...)
+	}
+
+	// Representations can only switch within an adaptation set, which holds
+	// one codec
+	var adaptationSets []string
+	var codecs []*videoCodec
+	codecStreams := map[*videoCodec][]string{}
+	for i, profile := range ladder {
+		codec := profileCodec(&profile)
+		if _, exists := codecStreams[codec]; !exists {
+			codecs = append(codecs, codec)
+		}
+		codecStreams[codec] = append(codecStreams[codec], strconv.Itoa(i))
+	}
+	for _, codec := range codecs {
+		adaptationSets = append(adaptationSets,
+			fmt.Sprintf("id=%d,streams=%s", len(adaptationSets), strings.Join(codecStreams[codec], ",")))
+	}
+
+	// Audio is encoded once, at the highest bitrate of the ladder, with an
+	// adaptation set per track so players can switch language
+	tracks := selectAudioTracks(output, inputInfo)
+	if len(tracks) > 0 {
+		args = append(args, audioMapArgs(tracks, 0)...)
+		args = append(args,
+			"-c:a", "aac",
+			"-b:a", fmt.Sprintf("%dk", ladderAudioBitrate(ladder)),
+		)
+	}
+	for j := range tracks {
+		adaptationSets = append(adaptationSets, fmt.Sprintf("id=%d,streams=%d", len(adaptationSets), len(ladder)+j))
+	}
+
+	// DASH-specific settings
+	args = append(args,
+		"-f", "dash",
+		"-seg_duration", strconv.Itoa(hlsSegmentLength(output)),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-init_seg_name", dashInitName,
+		"-media_seg_name", dashMediaName,
+		"-adaptation_sets", strings.Join(adaptationSets, " "),
+	)
+
+	// HLS playlists referencing the DASH segments
+	if output.HLSPlaylist {
+		args = append(args, "-hls_playlist", "1")
+	}
+
+	// Add extra args if configured
+	if len(ffmpegConfig.ExtraArgs) > 0 {
+		args = append(args, ffmpegConfig.ExtraArgs...)
+	}
+
+	// Output manifest
+	args = append(args, filepath.Join(outputDir, dashManifestName))
+
+	return args
+}
+
+// collectDASHFiles returns the manifest, segments and, if written, HLS
+// playlists of a DASH output. Segments are attributed to the profile of
+// their representation; the audio representations follow the video ones
+// and are attributed to their track.
+func (t *Transcoder) collectDASHFiles(outputDir string, ladder []config.ProfileConfig, tracks []audioTrack,
+	hlsPlaylist bool) ([]models.OutputFile, error) {
+	manifestFile, err := t.createOutputFile(filepath.Join(outputDir, dashManifestName), "application/dash+xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest file info: %w", err)
+	}
+	files := []models.OutputFile{*manifestFile}
+
+	if hlsPlaylist {
+		playlists, err := filepath.Glob(filepath.Join(outputDir, "*.m3u8"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find HLS playlists: %w", err)
+		}
+		for _, playlist := range playlists {
+			if file, err := t.createOutputFile(playlist, "application/vnd.apple.mpegurl"); err == nil {
+				files = append(files, *file)
+			}
+		}
+	}
+
+	segments, err := filepath.Glob(filepath.Join(outputDir, "*-*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find segment files: %w", err)
+	}
+	for _, segment := range segments {
+		matches := dashSegmentPattern.FindStringSubmatch(filepath.Base(segment))
+		if matches == nil {
+			continue
+		}
+
+		representation, _ := strconv.Atoi(matches[1] + matches[2])
+		isAudio := representation >= len(ladder)
+		profile := "audio"
+		switch {
+		case !isAudio:
+			profile = ladder[representation].Name
+		case representation-len(ladder) < len(tracks):
+			profile = tracks[representation-len(ladder)].name
+		}
+
+		mimeType := "video/iso.segment"
+		if matches[1] != "" {
+			mimeType = "video/mp4"
+			if isAudio {
+				mimeType = "audio/mp4"
+			}
+		}
+
+		if file, err := t.createOutputFile(segment, mimeType); err == nil {
+			file.Profile = profile
+			files = append(files, *file)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,124 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestBuildDASHFFmpegArgs(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96, Fit: FitSource},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, AudioBitrateKbps: 128, Fit: FitSource},
+	}
+	output := &config.OutputConfig{Name: "dash", Package: "dash", SegmentLengthS: 4, HLSPlaylist: true}
+
+	args := strings.Join(transcoder.buildDASHFFmpegArgs("in.mp4", "/out", ladder,
+		&VideoInfo{Width: 1920, Height: 1080, AudioCodec: "aac"}, output, config.JobFFmpegConfig{}, encodePass{}), " ")
+
+	for _, expected := range []string{
+		"-filter_complex [0:v]split=2[s0][s1];[s0]scale=640:360[v0];[s1]scale=1280:720[v1]",
+		"-map [v1] -c:v:1 libx264",
+		"-b:v:1 2500k",
+		"-map 0:a:0 -metadata:s:a:0 language=und -c:a aac -b:a 128k",
+		"-f dash -seg_duration 4",
+		"-adaptation_sets id=0,streams=0,1 id=1,streams=2",
+		"-hls_playlist 1",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in args: %s", expected, args)
+		}
+	}
+	if !strings.HasSuffix(args, "/out/manifest.mpd") {
+		t.Errorf("Expected manifest output, got %s", args)
+	}
+
+	// Sources without audio get a video adaptation set only
+	output.HLSPlaylist = false
+	args = strings.Join(transcoder.buildDASHFFmpegArgs("in.mp4", "/out", ladder,
+		&VideoInfo{Width: 1920, Height: 1080}, output, config.JobFFmpegConfig{}, encodePass{}), " ")
+	if strings.Contains(args, "0:a:0") || strings.Contains(args, "id=1") || strings.Contains(args, "-hls_playlist") {
+		t.Errorf("Expected video-only DASH without HLS playlists, got %s", args)
+	}
+
+	// Each codec gets its own adaptation set
+	ladder[1].Codec = "hevc"
+	args = strings.Join(transcoder.buildDASHFFmpegArgs("in.mp4", "/out", ladder,
+		&VideoInfo{Width: 1920, Height: 1080, AudioCodec: "aac"}, output, config.JobFFmpegConfig{}, encodePass{}), " ")
+	for _, expected := range []string{
+		"-c:v:1 libx265",
+		"-adaptation_sets id=0,streams=0 id=1,streams=1 id=2,streams=2",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in args: %s", expected, args)
+		}
+	}
+
+	// Each selected audio track gets its own adaptation set
+	output.AudioTracks = []string{"eng", "spa"}
+	args = strings.Join(transcoder.buildDASHFFmpegArgs("in.mp4", "/out", ladder,
+		&VideoInfo{Width: 1920, Height: 1080, AudioStreams: []AudioStream{
+			{Codec: "aac", Language: "spa"}, {Codec: "ac3", Language: "fre"}, {Codec: "aac", Language: "eng"},
+		}}, output, config.JobFFmpegConfig{}, encodePass{}), " ")
+	for _, expected := range []string{
+		"-map 0:a:2 -metadata:s:a:0 language=eng -map 0:a:0 -metadata:s:a:1 language=spa -c:a aac",
+		"-adaptation_sets id=0,streams=0 id=1,streams=1 id=2,streams=2 id=3,streams=3",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in args: %s", expected, args)
+		}
+	}
+}
+
+func TestCollectDASHFiles(t *testing.T) {
+	transcoder := &Transcoder{}
+	outputDir := t.TempDir()
+	for _, name := range []string{
+		"manifest.mpd", "master.m3u8", "media_0.m3u8",
+		"init-0.mp4", "chunk-0-00001.m4s", "init-1.mp4", "chunk-1-00001.m4s",
+		"init-2.mp4", "chunk-2-00001.m4s", "chunk-2-00002.m4s",
+		"manifest.mpd.tmp-1",
+	} {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ladder := []config.ProfileConfig{{Name: "360p"}, {Name: "720p"}}
+	tracks := []audioTrack{{name: "audio_eng"}}
+	files, err := transcoder.collectDASHFiles(outputDir, ladder, tracks, true)
+	if err != nil {
+		t.Fatalf("Failed to collect files: %v", err)
+	}
+
+	type fileInfo struct{ mimeType, profile string }
+	got := make(map[string]fileInfo)
+	for _, file := range files {
+		got[filepath.Base(file.Path)] = fileInfo{file.MimeType, file.Profile}
+	}
+
+	expected := map[string]fileInfo{
+		"manifest.mpd":      {"application/dash+xml", ""},
+		"master.m3u8":       {"application/vnd.apple.mpegurl", ""},
+		"media_0.m3u8":      {"application/vnd.apple.mpegurl", ""},
+		"init-0.mp4":        {"video/mp4", "360p"},
+		"chunk-0-00001.m4s": {"video/iso.segment", "360p"},
+		"init-1.mp4":        {"video/mp4", "720p"},
+		"chunk-1-00001.m4s": {"video/iso.segment", "720p"},
+		"init-2.mp4":        {"audio/mp4", "audio_eng"},
+		"chunk-2-00001.m4s": {"video/iso.segment", "audio_eng"},
+		"chunk-2-00002.m4s": {"video/iso.segment", "audio_eng"},
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d files, got %v", len(expected), got)
+	}
+	for name, info := range expected {
+		if got[name] != info {
+			t.Errorf("Expected %s as %+v, got %+v", name, info, got[name])
+		}
+	}
+}
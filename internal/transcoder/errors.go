@@ -0,0 +1,99 @@
+package transcoder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// stderrTailLines is the number of trailing stderr lines kept for error reports
+const stderrTailLines = 20
+
+// FFmpegError reports a failed ffmpeg or ffprobe run
+type FFmpegError struct {
+	Tool     string // ffmpeg or ffprobe
+	ExitCode int    // -1 if the process was killed by a signal
+	Stderr   string // Last lines of the process's stderr output
+	Err      error
+}
+
+func (e *FFmpegError) Error() string {
+	if line := lastLine(e.Stderr); line != "" {
+		return fmt.Sprintf("%s exited with code %d: %s", e.Tool, e.ExitCode, line)
+	}
+	return fmt.Sprintf("%s failed: %v", e.Tool, e.Err)
+}
+
+func (e *FFmpegError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether running the command again may succeed. A
+// non-zero exit means the input or options were rejected and will be again;
+// a process killed by a signal (e.g. the OOM killer) may succeed next time.
+func (e *FFmpegError) Retryable() bool {
+	return e.ExitCode < 0
+}
+
+// newFFmpegError wraps an error from running tool with its exit code and stderr tail
+func newFFmpegError(tool string, err error, stderr string) *FFmpegError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &FFmpegError{
+		Tool:     tool,
+		ExitCode: exitCode,
+		Stderr:   stderr,
+		Err:      err,
+	}
+}
+
+// stderrTail keeps the last lines written to a process's stderr
+type stderrTail struct {
+	lines []string
+}
+
+// add records a line, discarding the oldest once the tail is full
+func (t *stderrTail) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if len(t.lines) == stderrTailLines {
+		t.lines = t.lines[1:]
+	}
+	t.lines = append(t.lines, line)
+}
+
+// String returns the recorded lines joined by newlines
+func (t *stderrTail) String() string {
+	return strings.Join(t.lines, "\n")
+}
+
+// scanLines is a bufio.SplitFunc that splits on \n or \r, since ffmpeg
+// rewrites its progress line in place with carriage returns
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// lastLine returns the final line of s
+func lastLine(s string) string {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,9 +16,9 @@ import (
 )
 
 // transcodeHLS performs HLS (HTTP Live Streaming) transcoding
-func (t *Transcoder) transcodeHLS(ctx context.Context, inputPath string,
+func (t *Transcoder) transcodeHLS(ctx context.Context, inputPath string, captions []SidecarCaption,
 	output *config.OutputConfig, outputDir string, inputInfo *VideoInfo,
-	ffmpegConfig config.JobFFmpegConfig, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
+	ffmpegConfig config.JobFFmpegConfig, parallel bool, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
 
 	startTime := time.Now()
 	slog.Info("Starting HLS transcoding",
@@ -26,31 +27,55 @@ func (t *Transcoder) transcodeHLS(ctx context.Context, inputPath string,
 		"profiles", len(output.Profiles),
 	)
 
+	profiles := t.outputProfiles(output)
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles specified for HLS output")
+	}
+	ladder, skipped := resolveLadder(output, profiles, inputInfo)
+
 	var files []models.OutputFile
 	var totalFrames int
 
-	// If we have multiple profiles, create an adaptive bitrate ladder
-	if len(output.Profiles) > 0 {
-		// Create master playlist
-		masterPlaylistPath := filepath.Join(outputDir, "master.m3u8")
-		masterPlaylist, err := t.createMasterPlaylist(output.Profiles)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create master playlist: %w", err)
-		}
+	audio := hlsAudioFor(output, inputInfo)
+	audioRenditions := audio.renditions()
+	subtitles := selectSubtitleTracks(output, inputPath, inputInfo, captions)
 
-		if err := os.WriteFile(masterPlaylistPath, []byte(masterPlaylist), 0644); err != nil {
-			return nil, fmt.Errorf("failed to write master playlist: %w", err)
-		}
+	// An adaptive bitrate ladder, or a rendition with separate audio or
+	// subtitles, gets a master playlist listing the renditions actually
+	// produced and their codecs
+	writeMaster := len(output.Profiles) > 0 || audio.separate || len(subtitles) > 0
 
-		// Add master playlist to files
-		masterFile, err := t.createOutputFile(masterPlaylistPath, "application/vnd.apple.mpegurl")
+	if output.SinglePass {
+		slog.Info("Transcoding HLS ladder in a single pass", "renditions", len(ladder))
+
+		passFiles, frames, err := t.transcodeHLSSinglePass(ctx, inputPath, ladder, outputDir,
+			inputInfo, output, ffmpegConfig, progressCallback)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create master playlist file info: %w", err)
+			return nil, fmt.Errorf("failed to transcode HLS ladder: %w", err)
 		}
-		files = append(files, *masterFile)
+		files = passFiles
+		totalFrames = frames
+	} else {
+		// Transcode each rendition of the ladder, then any separate audio
+		// renditions
+		renditionFiles := make([][]models.OutputFile, len(ladder)+len(audioRenditions))
+		progress := newProgressStages(progressCallback, len(renditionFiles))
+		err := forEach(ctx, len(renditionFiles), parallel, func(ctx context.Context, i int) error {
+			if i >= len(ladder) {
+				track := audioRenditions[i-len(ladder)]
+				slog.Info("Transcoding HLS audio rendition", "track", track.name, "language", track.language)
+
+				trackFiles, err := t.transcodeHLSAudio(ctx, inputPath, track, ladderAudioBitrate(ladder),
+					outputDir, output, progress.stage(i))
+				if err != nil {
+					return fmt.Errorf("failed to transcode HLS audio rendition '%s': %w", track.name, err)
+				}
+				progress.complete(i)
+				renditionFiles[i] = trackFiles
+				return nil
+			}
 
-		// Transcode each profile
-		for i, profile := range output.Profiles {
+			profile := ladder[i]
 			slog.Info("Transcoding HLS profile",
 				"profile", profile.Name,
 				"resolution", fmt.Sprintf("%dx%d", profile.Width, profile.Height),
@@ -58,35 +83,52 @@ func (t *Transcoder) transcodeHLS(ctx context.Context, inputPath string,
 			)
 
 			profileFiles, frames, err := t.transcodeHLSProfile(ctx, inputPath, &profile,
-				outputDir, inputInfo, output, ffmpegConfig, progressCallback)
+				outputDir, inputInfo, output, ffmpegConfig, progress.stage(i))
 			if err != nil {
-				return nil, fmt.Errorf("failed to transcode HLS profile '%s': %w", profile.Name, err)
+				return fmt.Errorf("failed to transcode HLS profile '%s': %w", profile.Name, err)
 			}
+			progress.complete(i)
 
-			files = append(files, profileFiles...)
+			renditionFiles[i] = profileFiles
 			if i == 0 { // Use first profile for total frame count
 				totalFrames = frames
 			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, profileFiles := range renditionFiles {
+			files = append(files, profileFiles...)
 		}
-	} else if output.Profile != "" {
-		// Single profile output
-		slog.Info("Transcoding single HLS profile", "profile", output.Profile)
+	}
 
-		// Create a profile config from the single profile name
-		// This is a simplified approach - in a real implementation, you might have
-		// predefined profiles or derive settings from the profile name
-		profile := t.getProfileByName(output.Profile)
+	// Subtitles are converted once the renditions they accompany exist
+	if len(subtitles) > 0 {
+		subtitleFiles, err := t.transcodeHLSSubtitles(ctx, subtitles, inputInfo, outputDir, output)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, subtitleFiles...)
+	}
 
-		profileFiles, frames, err := t.transcodeHLSProfile(ctx, inputPath, &profile,
-			outputDir, inputInfo, output, ffmpegConfig, progressCallback)
+	if writeMaster {
+		masterPlaylistPath := filepath.Join(outputDir, "master.m3u8")
+		peaks := measureSegmentPeaks(outputDir, ladder)
+		masterPlaylist, err := t.createMasterPlaylist(ladder, inputInfo, audio, subtitles, peaks)
 		if err != nil {
-			return nil, fmt.Errorf("failed to transcode HLS profile '%s': %w", output.Profile, err)
+			return nil, fmt.Errorf("failed to create master playlist: %w", err)
 		}
 
-		files = append(files, profileFiles...)
-		totalFrames = frames
-	} else {
-		return nil, fmt.Errorf("no profiles specified for HLS output")
+		if err := os.WriteFile(masterPlaylistPath, []byte(masterPlaylist), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write master playlist: %w", err)
+		}
+
+		masterFile, err := t.createOutputFile(masterPlaylistPath, "application/vnd.apple.mpegurl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create master playlist file info: %w", err)
+		}
+		files = append([]models.OutputFile{*masterFile}, files...)
 	}
 
 	result := &models.ConversionOutput{
@@ -96,11 +138,22 @@ func (t *Transcoder) transcodeHLS(ctx context.Context, inputPath string,
 		Files:   files,
 		Metadata: map[string]string{
 			"package":         "hls",
+			"single_pass":     strconv.FormatBool(output.SinglePass),
+			"container":       hlsSegmentsFor(output).container(),
 			"segment_length":  strconv.Itoa(output.SegmentLengthS),
 			"total_frames":    strconv.Itoa(totalFrames),
 			"processing_time": time.Since(startTime).String(),
 		},
 	}
+	if len(skipped) > 0 {
+		result.Metadata["skipped_profiles"] = strings.Join(skipped, ",")
+	}
+	if len(audio.tracks) > 0 {
+		result.Metadata["audio_tracks"] = audioTrackNames(audio.tracks)
+	}
+	if len(subtitles) > 0 {
+		result.Metadata["subtitle_tracks"] = subtitleTrackNames(subtitles)
+	}
 
 	slog.Info("HLS transcoding completed",
 		"outputName", output.Name,
@@ -123,63 +176,122 @@ func (t *Transcoder) transcodeHLSProfile(ctx context.Context, inputPath string,
 		return nil, 0, fmt.Errorf("failed to create profile directory: %w", err)
 	}
 
-	// Set default segment length if not specified
-	segmentLength := output.SegmentLengthS
-	if segmentLength == 0 {
-		segmentLength = 6 // Default 6 second segments
+	// Run FFmpeg with progress monitoring, twice for two-pass encodes
+	passes := encodePasses([]config.ProfileConfig{*profile}, passLogFile(outputDir, profile.Name))
+	err := t.runEncodePasses(ctx, passes, func(pass encodePass) []string {
+		args := t.buildHLSFFmpegArgs(inputPath, profileDir, profile, inputInfo, output, ffmpegConfig, pass)
+		slog.Debug("Running FFmpeg for HLS",
+			"profile", profile.Name,
+			"pass", pass.number,
+			"args", strings.Join(args, " "),
+		)
+		return args
+	}, inputInfo.TotalFrames, progressCallback)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ffmpeg execution failed: %w", err)
 	}
 
-	// Build FFmpeg command for HLS
-	args := t.buildHLSFFmpegArgs(inputPath, profileDir, profile, segmentLength, ffmpegConfig)
-
-	slog.Debug("Running FFmpeg for HLS",
-		"profile", profile.Name,
-		"args", strings.Join(args, " "),
-	)
-
-	// Run FFmpeg with progress monitoring
-	if err := t.runFFmpegWithProgress(ctx, args, inputInfo.TotalFrames, progressCallback); err != nil {
-		return nil, 0, fmt.Errorf("ffmpeg execution failed: %w", err)
+	files, err := t.collectHLSProfileFiles(profileDir, profile.Name, hlsSegmentsFor(output))
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Collect output files
+	return files, inputInfo.TotalFrames, nil
+}
+
+// collectHLSProfileFiles returns the playlist, init section and segments
+// of a rendition
+func (t *Transcoder) collectHLSProfileFiles(profileDir, profileName string, segments hlsSegments) ([]models.OutputFile, error) {
 	var files []models.OutputFile
 
 	// Add playlist file
-	playlistPath := filepath.Join(profileDir, fmt.Sprintf("%s.m3u8", profile.Name))
+	playlistPath := filepath.Join(profileDir, fmt.Sprintf("%s.m3u8", profileName))
 	if playlistFile, err := t.createOutputFile(playlistPath, "application/vnd.apple.mpegurl"); err == nil {
+		playlistFile.Profile = profileName
 		files = append(files, *playlistFile)
 	}
 
+	// Add the init section that fMP4 playlists reference with #EXT-X-MAP
+	if segments.fragmented {
+		initFile, err := t.createOutputFile(filepath.Join(profileDir, profileName+"_init.mp4"), "video/mp4")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find init segment: %w", err)
+		}
+		initFile.Profile = profileName
+		files = append(files, *initFile)
+	}
+
 	// Add segment files
-	segmentPattern := filepath.Join(profileDir, fmt.Sprintf("%s_*.ts", profile.Name))
+	segmentPattern := filepath.Join(profileDir, fmt.Sprintf("%s_*.%s", profileName, segments.extension))
 	segmentFiles, err := filepath.Glob(segmentPattern)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to find segment files: %w", err)
+		return nil, fmt.Errorf("failed to find segment files: %w", err)
 	}
 
 	for _, segmentFile := range segmentFiles {
-		if file, err := t.createOutputFile(segmentFile, "video/mp2t"); err == nil {
+		if file, err := t.createOutputFile(segmentFile, segments.mimeType); err == nil {
+			file.Profile = profileName
 			files = append(files, *file)
 		}
 	}
 
-	return files, inputInfo.TotalFrames, nil
+	return files, nil
 }
 
-// buildHLSFFmpegArgs builds FFmpeg arguments for HLS transcoding
-func (t *Transcoder) buildHLSFFmpegArgs(inputPath, outputDir string, profile *config.ProfileConfig,
-	segmentLength int, ffmpegConfig config.JobFFmpegConfig) []string {
+// hlsSegments describes the segment files of an HLS container
+type hlsSegments struct {
+	fragmented bool   // fMP4 (CMAF) segments with an init section, else MPEG-TS
+	extension  string // Segment file extension
+	mimeType   string // Segment MIME type
+}
 
-	profileName := profile.Name
-	playlistPath := filepath.Join(outputDir, fmt.Sprintf("%s.m3u8", profileName))
-	segmentPath := filepath.Join(outputDir, fmt.Sprintf("%s_%%03d.ts", profileName))
+// hlsSegmentsFor returns the segment format of an HLS output's container:
+// fMP4 for "fmp4" or "cmaf", MPEG-TS otherwise
+func hlsSegmentsFor(output *config.OutputConfig) hlsSegments {
+	switch strings.ToLower(output.Container) {
+	case "fmp4", "cmaf":
+		return hlsSegments{fragmented: true, extension: "m4s", mimeType: "video/iso.segment"}
+	default:
+		return hlsSegments{extension: "ts", mimeType: "video/mp2t"}
+	}
+}
+
+// container returns the container name reported in output metadata
+func (s hlsSegments) container() string {
+	if s.fragmented {
+		return "fmp4"
+	}
+	return "ts"
+}
 
-	args := []string{
-		"-i", inputPath,
-		"-c:v", "libx264",
-		"-c:a", "aac",
+// args returns the ffmpeg options that write the segments of the variant
+// called name to dir. fMP4 variants get a name_init.mp4 init section.
+func (s hlsSegments) args(dir, name string) []string {
+	var args []string
+	if s.fragmented {
+		args = append(args,
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", name+"_init.mp4",
+		)
 	}
+	return append(args, "-hls_segment_filename", filepath.Join(dir, fmt.Sprintf("%s_%%03d.%s", name, s.extension)))
+}
+
+// hlsSegmentLength returns the output's segment length in seconds
+func hlsSegmentLength(output *config.OutputConfig) int {
+	if output.SegmentLengthS == 0 {
+		return 6 // Default 6 second segments
+	}
+	return output.SegmentLengthS
+}
+
+// buildHLSFFmpegArgs builds FFmpeg arguments for one pass of HLS transcoding
+func (t *Transcoder) buildHLSFFmpegArgs(inputPath, outputDir string, profile *config.ProfileConfig,
+	inputInfo *VideoInfo, output *config.OutputConfig, ffmpegConfig config.JobFFmpegConfig, pass encodePass) []string {
+
+	playlistPath := filepath.Join(outputDir, fmt.Sprintf("%s.m3u8", profile.Name))
+
+	args := []string{"-i", inputPath, "-map", "0:v:0"}
 
 	// Add hardware acceleration if configured
 	if ffmpegConfig.HWAccel != "" {
@@ -187,37 +299,36 @@ func (t *Transcoder) buildHLSFFmpegArgs(inputPath, outputDir string, profile *co
 	}
 
 	// Video encoding settings
-	args = append(args,
-		"-vf", fmt.Sprintf("scale=%d:%d", profile.Width, profile.Height),
-		"-b:v", fmt.Sprintf("%dk", profile.VideoBitrateKbps),
-		"-maxrate", fmt.Sprintf("%dk", profile.VideoBitrateKbps),
-		"-bufsize", fmt.Sprintf("%dk", profile.VideoBitrateKbps*2),
-		"-profile:v", "main",
-		"-level", "4.0",
-	)
+	args = append(args, "-vf", videoFilter(profile, inputInfo))
+	args = append(args, videoStreamArgs(profile, inputInfo, "", hlsSegmentLength(output), ffmpegConfig.Preset, pass)...)
+	if pass.analysis() {
+		return append(args, analysisOutputArgs()...)
+	}
 
-	// Audio encoding settings
-	if profile.AudioBitrateKbps > 0 {
+	// Audio encoding settings; separate audio renditions leave the video
+	// renditions without audio
+	if track := hlsAudioFor(output, inputInfo).muxedTrack(); track != nil {
+		audioBitrate := profile.AudioBitrateKbps
+		if audioBitrate <= 0 {
+			audioBitrate = 128
+		}
+		args = append(args, audioMapArgs([]audioTrack{*track}, 0)...)
 		args = append(args,
-			"-b:a", fmt.Sprintf("%dk", profile.AudioBitrateKbps),
+			"-c:a", "aac",
+			"-b:a", fmt.Sprintf("%dk", audioBitrate),
 		)
 	} else {
-		args = append(args, "-b:a", "128k")
+		args = append(args, "-an")
 	}
 
 	// HLS-specific settings
 	args = append(args,
 		"-f", "hls",
-		"-hls_time", strconv.Itoa(segmentLength),
+		"-hls_time", strconv.Itoa(hlsSegmentLength(output)),
 		"-hls_list_size", "0",
-		"-hls_segment_filename", segmentPath,
 		"-hls_flags", "independent_segments",
 	)
-
-	// Add preset if configured
-	if ffmpegConfig.Preset != "" {
-		args = append(args, "-preset", ffmpegConfig.Preset)
-	}
+	args = append(args, hlsSegmentsFor(output).args(outputDir, profile.Name)...)
 
 	// Add extra args if configured
 	if len(ffmpegConfig.ExtraArgs) > 0 {
@@ -230,25 +341,148 @@ func (t *Transcoder) buildHLSFFmpegArgs(inputPath, outputDir string, profile *co
 	return args
 }
 
-// createMasterPlaylist creates an HLS master playlist for multiple profiles
-func (t *Transcoder) createMasterPlaylist(profiles []config.ProfileConfig) (string, error) {
+// createMasterPlaylist creates an HLS master playlist for multiple profiles.
+// Separate audio renditions are listed as an EXT-X-MEDIA group that every
+// video rendition refers to, followed by the audio-only rendition if any.
+// Subtitle renditions are listed as an EXT-X-MEDIA subtitles group.
+// BANDWIDTH is the larger of the rendition's bitrate cap and its measured
+// segment peak in peaks, keyed by profile name; constant quality
+// renditions have no cap and declare their measured peak.
+func (t *Transcoder) createMasterPlaylist(profiles []config.ProfileConfig, inputInfo *VideoInfo, audio hlsAudio,
+	subtitles []subtitleTrack, peaks map[string]int) (string, error) {
 	var playlist strings.Builder
 
 	playlist.WriteString("#EXTM3U\n")
 	playlist.WriteString("#EXT-X-VERSION:6\n\n")
 
+	audioGroup := ""
+	audioBitrate := ladderAudioBitrate(profiles)
+	if renditions := audio.renditions(); len(renditions) > 0 {
+		audioGroup = `,AUDIO="audio"`
+		for _, track := range renditions {
+			playlist.WriteString(fmt.Sprintf("#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"%s\",LANGUAGE=\"%s\",DEFAULT=%s,AUTOSELECT=YES,URI=\"%s/%s.m3u8\"\n",
+				track.label, track.language, yesNo(track.isDefault), track.name, track.name))
+		}
+		playlist.WriteString("\n")
+	}
+
+	subtitleGroup := ""
+	if len(subtitles) > 0 {
+		subtitleGroup = `,SUBTITLES="subs"`
+		for _, track := range subtitles {
+			playlist.WriteString(fmt.Sprintf("#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"%s\",LANGUAGE=\"%s\",DEFAULT=%s,AUTOSELECT=YES,FORCED=%s,URI=\"%s/%s.m3u8\"\n",
+				track.label, track.language, yesNo(track.isDefault), yesNo(track.forced), track.name, track.name))
+		}
+		playlist.WriteString("\n")
+	}
+
 	for _, profile := range profiles {
-		// Calculate bandwidth (video + audio bitrate in bits per second)
-		bandwidth := (profile.VideoBitrateKbps + profile.AudioBitrateKbps) * 1000
+		// Separate audio renditions add the highest audio bitrate, while
+		// measured segments already carry muxed audio
+		audioKbps, measuredAudioKbps := profile.AudioBitrateKbps, 0
+		if audioGroup != "" {
+			audioKbps, measuredAudioKbps = audioBitrate, audioBitrate
+		}
+
+		videoKbps := peakVideoBitrate(&profile)
+		peak, measured := peaks[profile.Name]
+		if videoKbps == 0 && !measured {
+			// An unmeasured constant quality rendition declares its
+			// nominal bitrate
+			videoKbps = profile.VideoBitrateKbps
+		}
+		bandwidth := (videoKbps + audioKbps) * 1000
+		if measured {
+			bandwidth = max(bandwidth, (peak+measuredAudioKbps)*1000)
+		}
 
-		playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,NAME=\"%s\"\n",
-			bandwidth, profile.Width, profile.Height, profile.Name))
+		playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\"%s,NAME=\"%s\"\n",
+			bandwidth, profile.Width, profile.Height, renditionCodecs(&profile, inputInfo, "hls", true), audioGroup+subtitleGroup, profile.Name))
 		playlist.WriteString(fmt.Sprintf("%s/%s.m3u8\n\n", profile.Name, profile.Name))
 	}
 
+	// Low-bandwidth clients can fall back to the default audio rendition
+	if audio.audioOnly {
+		for _, track := range audio.renditions() {
+			if !track.isDefault {
+				continue
+			}
+			_, audioCodecs := audioCodecFor("hls")
+			playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\"%s,NAME=\"audio\"\n",
+				audioBitrate*1000, audioCodecs, audioGroup))
+			playlist.WriteString(fmt.Sprintf("%s/%s.m3u8\n\n", track.name, track.name))
+		}
+	}
+
 	return playlist.String(), nil
 }
 
+// measureSegmentPeaks returns the peak bitrate, in kbps, of each profile's
+// segments under outputDir. HLS defines BANDWIDTH as the highest bitrate of
+// any segment, which only the encoded segments tell for uncapped encodes.
+// Renditions that cannot be measured are left out.
+func measureSegmentPeaks(outputDir string, profiles []config.ProfileConfig) map[string]int {
+	peaks := make(map[string]int)
+	for _, profile := range profiles {
+		playlistPath := filepath.Join(outputDir, profile.Name, profile.Name+".m3u8")
+		peak, err := segmentPeakBitrate(playlistPath)
+		if err != nil {
+			slog.Warn("Failed to measure HLS segment bitrate",
+				"profile", profile.Name,
+				"error", err,
+			)
+			continue
+		}
+		peaks[profile.Name] = peak
+	}
+	return peaks
+}
+
+// segmentPeakBitrate returns the highest bitrate, in kbps, of the segments
+// listed in a media playlist: each segment's size over its EXTINF duration
+func segmentPeakBitrate(playlistPath string) (int, error) {
+	data, err := os.ReadFile(playlistPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	peak := 0
+	duration := 0.0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if duration, err = strconv.ParseFloat(value, 64); err != nil {
+				return 0, fmt.Errorf("invalid segment duration: %s", line)
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			info, err := os.Stat(filepath.Join(filepath.Dir(playlistPath), line))
+			if err != nil {
+				return 0, fmt.Errorf("failed to stat segment: %w", err)
+			}
+			if duration > 0 {
+				peak = max(peak, int(math.Ceil(float64(info.Size())*8/duration/1000)))
+			}
+			duration = 0
+		}
+	}
+
+	if peak == 0 {
+		return 0, fmt.Errorf("no segments in %s", playlistPath)
+	}
+	return peak, nil
+}
+
+// yesNo formats a playlist attribute flag
+func yesNo(value bool) string {
+	if value {
+		return "YES"
+	}
+	return "NO"
+}
+
 // getProfileByName returns a profile configuration by name (simplified implementation)
 func (t *Transcoder) getProfileByName(profileName string) config.ProfileConfig {
 	// This is a simplified implementation. In a real system, you would
@@ -0,0 +1,147 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// transcodeHLSSinglePass encodes every rendition of the ladder with one
+// ffmpeg process, so the source is decoded once and split between the
+// renditions. The output layout matches per-profile encoding.
+func (t *Transcoder) transcodeHLSSinglePass(ctx context.Context, inputPath string,
+	ladder []config.ProfileConfig, outputDir string, inputInfo *VideoInfo,
+	output *config.OutputConfig, ffmpegConfig config.JobFFmpegConfig,
+	progressCallback ProgressCallback) ([]models.OutputFile, int, error) {
+
+	// Variant streams are named after their profile or audio track
+	names := make([]string, 0, len(ladder))
+	for _, profile := range ladder {
+		names = append(names, profile.Name)
+	}
+	for _, track := range hlsAudioFor(output, inputInfo).renditions() {
+		names = append(names, track.name)
+	}
+
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Join(outputDir, name), 0755); err != nil {
+			return nil, 0, fmt.Errorf("failed to create profile directory: %w", err)
+		}
+	}
+
+	// All renditions advance together, so the frame count of the shared
+	// decode is the progress of the whole ladder
+	passes := encodePasses(ladder, passLogFile(outputDir, "hls"))
+	err := t.runEncodePasses(ctx, passes, func(pass encodePass) []string {
+		args := t.buildHLSSinglePassArgs(inputPath, outputDir, ladder, inputInfo, output, ffmpegConfig, pass)
+		slog.Debug("Running FFmpeg for single-pass HLS",
+			"renditions", len(ladder),
+			"pass", pass.number,
+			"args", strings.Join(args, " "),
+		)
+		return args
+	}, inputInfo.TotalFrames, progressCallback)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ffmpeg execution failed: %w", err)
+	}
+
+	var files []models.OutputFile
+	for _, name := range names {
+		profileFiles, err := t.collectHLSProfileFiles(filepath.Join(outputDir, name), name,
+			hlsSegmentsFor(output))
+		if err != nil {
+			return nil, 0, err
+		}
+		files = append(files, profileFiles...)
+	}
+
+	return files, inputInfo.TotalFrames, nil
+}
+
+// buildHLSSinglePassArgs builds FFmpeg arguments that split the decoded
+// source into one scaled video stream per rendition and map each with its
+// own audio encode into an HLS variant stream named after the profile.
+// Separate audio renditions follow as audio-only variant streams named
+// after their track. The analysis pass of a two-pass encode maps the video
+// streams only.
+func (t *Transcoder) buildHLSSinglePassArgs(inputPath, outputDir string, ladder []config.ProfileConfig,
+	inputInfo *VideoInfo, output *config.OutputConfig, ffmpegConfig config.JobFFmpegConfig, pass encodePass) []string {
+
+	args := []string{"-i", inputPath}
+
+	// Add hardware acceleration if configured
+	if ffmpegConfig.HWAccel != "" {
+		args = append([]string{"-hwaccel", ffmpegConfig.HWAccel}, args...)
+	}
+
+	args = append(args, "-filter_complex", splitFilter(ladder, inputInfo))
+
+	audio := hlsAudioFor(output, inputInfo)
+	muxed := audio.muxedTrack()
+	streamMap := make([]string, 0, len(ladder)+len(audio.renditions()))
+	for i, profile := range ladder {
+		args = append(args, "-map", fmt.Sprintf("[v%d]", i))
+		args = append(args, videoStreamArgs(&profile, inputInfo, fmt.Sprintf(":%d", i), hlsSegmentLength(output),
+			ffmpegConfig.Preset, pass)...)
+
+		if muxed == nil || pass.analysis() {
+			streamMap = append(streamMap, fmt.Sprintf("v:%d,name:%s", i, profile.Name))
+			continue
+		}
+
+		audioBitrate := profile.AudioBitrateKbps
+		if audioBitrate <= 0 {
+			audioBitrate = 128
+		}
+		args = append(args, audioMapArgs([]audioTrack{*muxed}, i)...)
+		args = append(args, fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", audioBitrate))
+		streamMap = append(streamMap, fmt.Sprintf("v:%d,a:%d,name:%s", i, i, profile.Name))
+	}
+	if pass.analysis() {
+		return append(args, analysisOutputArgs()...)
+	}
+
+	// Separate audio renditions are variant streams of their own, encoded
+	// once at the ladder's audio bitrate
+	renditions := audio.renditions()
+	args = append(args, audioMapArgs(renditions, 0)...)
+	for j, track := range renditions {
+		args = append(args, fmt.Sprintf("-b:a:%d", j), fmt.Sprintf("%dk", ladderAudioBitrate(ladder)))
+		streamMap = append(streamMap, fmt.Sprintf("a:%d,name:%s", j, track.name))
+	}
+
+	if len(audio.tracks) > 0 {
+		args = append(args, "-c:a", "aac")
+	}
+
+	// HLS-specific settings; %v is replaced with each variant's name. There
+	// is no -master_pl_name: ffmpeg leaves out variants without a bitrate,
+	// such as crf renditions, and cannot list the WebVTT tracks written
+	// after it, so the master playlist comes from createMasterPlaylist as
+	// in per-profile mode.
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentLength(output)),
+		"-hls_list_size", "0",
+		"-hls_flags", "independent_segments",
+		"-var_stream_map", strings.Join(streamMap, " "),
+	)
+	args = append(args, hlsSegmentsFor(output).args(filepath.Join(outputDir, "%v"), "%v")...)
+
+	// Add extra args if configured
+	if len(ffmpegConfig.ExtraArgs) > 0 {
+		args = append(args, ffmpegConfig.ExtraArgs...)
+	}
+
+	// Output playlists
+	args = append(args, filepath.Join(outputDir, "%v", "%v.m3u8"))
+
+	return args
+}
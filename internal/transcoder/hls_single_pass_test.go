@@ -0,0 +1,68 @@
+package transcoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestBuildHLSSinglePassArgs(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96, Fit: FitSource},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, Fit: FitSource},
+	}
+	inputInfo := &VideoInfo{Width: 1920, Height: 1080, AudioCodec: "aac"}
+
+	args := transcoder.buildHLSSinglePassArgs("in.mp4", "/out", ladder, inputInfo,
+		&config.OutputConfig{SegmentLengthS: 4}, config.JobFFmpegConfig{Preset: "fast"}, encodePass{})
+
+	value := func(flag string) string {
+		t.Helper()
+		for i, arg := range args {
+			if arg == flag && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+		t.Fatalf("Expected %s in args: %v", flag, args)
+		return ""
+	}
+
+	expectedFilter := "[0:v]split=2[s0][s1];[s0]scale=640:360[v0];[s1]scale=1280:720[v1]"
+	if filter := value("-filter_complex"); filter != expectedFilter {
+		t.Errorf("Expected filter %q, got %q", expectedFilter, filter)
+	}
+	if streamMap := value("-var_stream_map"); streamMap != "v:0,a:0,name:360p v:1,a:1,name:720p" {
+		t.Errorf("Unexpected var_stream_map %q", streamMap)
+	}
+	if bitrate := value("-b:v:1"); bitrate != "2500k" {
+		t.Errorf("Expected second rendition at 2500k, got %s", bitrate)
+	}
+	if bitrate := value("-b:a:1"); bitrate != "128k" {
+		t.Errorf("Expected default audio bitrate for second rendition, got %s", bitrate)
+	}
+	if encoder := value("-c:v:1"); encoder != "libx264" {
+		t.Errorf("Expected second rendition encoded with libx264, got %s", encoder)
+	}
+	if level := value("-level:v:0"); level != "3.0" {
+		t.Errorf("Expected level 3.0 for 360p, got %s", level)
+	}
+	if preset := value("-preset:v:1"); preset != "fast" {
+		t.Errorf("Expected preset for second rendition, got %s", preset)
+	}
+	if last := args[len(args)-1]; last != "/out/%v/%v.m3u8" {
+		t.Errorf("Expected variant playlist output, got %s", last)
+	}
+
+	// Sources without audio map video only
+	args = transcoder.buildHLSSinglePassArgs("in.mp4", "/out", ladder, &VideoInfo{Width: 1920, Height: 1080},
+		&config.OutputConfig{SegmentLengthS: 4}, config.JobFFmpegConfig{}, encodePass{})
+	joined := strings.Join(args, " ")
+	if strings.Contains(joined, "0:a:0") {
+		t.Errorf("Expected video-only variants, got %v", args)
+	}
+	if !strings.Contains(joined, "v:0,name:360p v:1,name:720p") {
+		t.Errorf("Expected video-only var_stream_map, got %v", args)
+	}
+}
@@ -0,0 +1,127 @@
+package transcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestBuildHLSFFmpegArgs_Containers(t *testing.T) {
+	transcoder := &Transcoder{}
+	profile := &config.ProfileConfig{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, Fit: FitSource}
+	inputInfo := &VideoInfo{Width: 1920, Height: 1080}
+
+	args := strings.Join(transcoder.buildHLSFFmpegArgs("in.mp4", "/out/720p", profile, inputInfo,
+		&config.OutputConfig{}, config.JobFFmpegConfig{}, encodePass{}), " ")
+	if !strings.Contains(args, "-hls_segment_filename /out/720p/720p_%03d.ts") || strings.Contains(args, "fmp4") {
+		t.Errorf("Expected MPEG-TS segments by default, got %s", args)
+	}
+
+	for _, container := range []string{"fmp4", "CMAF"} {
+		args = strings.Join(transcoder.buildHLSFFmpegArgs("in.mp4", "/out/720p", profile, inputInfo,
+			&config.OutputConfig{Container: container}, config.JobFFmpegConfig{}, encodePass{}), " ")
+		expected := "-hls_segment_type fmp4 -hls_fmp4_init_filename 720p_init.mp4 -hls_segment_filename /out/720p/720p_%03d.m4s"
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected fMP4 segment options for container %s, got %s", container, args)
+		}
+	}
+}
+
+func TestCollectHLSProfileFiles_FMP4(t *testing.T) {
+	transcoder := &Transcoder{}
+	profileDir := t.TempDir()
+	for _, name := range []string{"720p.m3u8", "720p_init.mp4", "720p_000.m4s", "720p_001.m4s", "stray.ts"} {
+		if err := os.WriteFile(filepath.Join(profileDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := transcoder.collectHLSProfileFiles(profileDir, "720p", hlsSegmentsFor(&config.OutputConfig{Container: "fmp4"}))
+	if err != nil {
+		t.Fatalf("Failed to collect files: %v", err)
+	}
+
+	mimeTypes := make(map[string]string)
+	for _, file := range files {
+		mimeTypes[filepath.Base(file.Path)] = file.MimeType
+		if file.Profile != "720p" {
+			t.Errorf("Expected %s to belong to profile 720p, got %q", file.Path, file.Profile)
+		}
+	}
+
+	expected := map[string]string{
+		"720p.m3u8":     "application/vnd.apple.mpegurl",
+		"720p_init.mp4": "video/mp4",
+		"720p_000.m4s":  "video/iso.segment",
+		"720p_001.m4s":  "video/iso.segment",
+	}
+	if len(mimeTypes) != len(expected) {
+		t.Errorf("Expected files %v, got %v", expected, mimeTypes)
+	}
+	for name, mimeType := range expected {
+		if mimeTypes[name] != mimeType {
+			t.Errorf("Expected %s with MIME type %s, got %q", name, mimeType, mimeTypes[name])
+		}
+	}
+
+	// A missing init section means the playlist cannot be played
+	os.Remove(filepath.Join(profileDir, "720p_init.mp4"))
+	if _, err := transcoder.collectHLSProfileFiles(profileDir, "720p", hlsSegmentsFor(&config.OutputConfig{Container: "fmp4"})); err == nil {
+		t.Error("Expected an error without the init section")
+	}
+}
+
+func TestCreateMasterPlaylist_Bandwidth(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{
+		{Name: "1080p", Width: 1920, Height: 1080, RateControl: RateCRF, CRF: crf(23), AudioBitrateKbps: 128},
+		{Name: "720p", Width: 1280, Height: 720, RateControl: RateTwoPass, VideoBitrateKbps: 2500, MaxBitrateKbps: 4000, AudioBitrateKbps: 128},
+		{Name: "480p", Width: 854, Height: 480, RateControl: RateCappedCRF, VideoBitrateKbps: 1200, AudioBitrateKbps: 96},
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96},
+	}
+	// Measured peaks include muxed audio
+	peaks := map[string]int{"1080p": 7100, "720p": 3900, "360p": 1100}
+
+	playlist, err := transcoder.createMasterPlaylist(ladder, &VideoInfo{FrameRate: 30}, hlsAudio{}, nil, peaks)
+	if err != nil {
+		t.Fatalf("Failed to create master playlist: %v", err)
+	}
+	for _, expected := range []string{
+		"BANDWIDTH=7100000,RESOLUTION=1920x1080", // Uncapped, the measured peak
+		"BANDWIDTH=4128000,RESOLUTION=1280x720",  // The two-pass cap
+		"BANDWIDTH=1296000,RESOLUTION=854x480",   // Capped at the bitrate, not measured
+		"BANDWIDTH=1100000,RESOLUTION=640x360",   // Measured above the cap
+	} {
+		if !strings.Contains(playlist, expected) {
+			t.Errorf("Expected %s in master playlist, got:\n%s", expected, playlist)
+		}
+	}
+}
+
+func TestSegmentPeakBitrate(t *testing.T) {
+	dir := t.TempDir()
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.000000,\n720p_000.ts\n#EXTINF:2.000000,\n720p_001.ts\n#EXT-X-ENDLIST\n"
+	files := map[string]int{"720p.m3u8": 0, "720p_000.ts": 750000, "720p_001.ts": 500000}
+	for name, size := range files {
+		data := make([]byte, size)
+		if name == "720p.m3u8" {
+			data = []byte(playlist)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 750 kB over 6s is 1000 kbps, 500 kB over 2s is 2000 kbps
+	peak, err := segmentPeakBitrate(filepath.Join(dir, "720p.m3u8"))
+	if err != nil || peak != 2000 {
+		t.Errorf("Expected a peak of 2000 kbps, got %d (%v)", peak, err)
+	}
+
+	if _, err := segmentPeakBitrate(filepath.Join(dir, "missing.m3u8")); err == nil {
+		t.Error("Expected an error for a missing playlist")
+	}
+}
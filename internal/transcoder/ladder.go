@@ -0,0 +1,250 @@
+package transcoder
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+// Upscale policies for profiles larger than the source video
+const (
+	UpscaleSkip  = "skip"  // Drop the profile (default)
+	UpscaleCap   = "cap"   // Encode the profile at the source resolution
+	UpscaleAllow = "allow" // Encode the profile as configured
+)
+
+// Fit modes for sizing a rendition within its profile's box
+const (
+	FitSource  = "source"  // Fit inside the box, keeping the source aspect ratio (default)
+	FitWidth   = "width"   // Scale to the box width; the height follows the source aspect ratio
+	FitContain = "contain" // Fit inside the box and pad the rest (letterbox or pillarbox)
+	FitCover   = "cover"   // Fill the box and crop what overflows
+)
+
+// outputProfiles returns the profiles an output is configured with: its
+// ladder, or the predefined profile named by Profile
+func (t *Transcoder) outputProfiles(output *config.OutputConfig) []config.ProfileConfig {
+	if len(output.Profiles) > 0 {
+		return output.Profiles
+	}
+	if output.Profile != "" {
+		return []config.ProfileConfig{t.getProfileByName(output.Profile)}
+	}
+	return nil
+}
+
+// resolveLadder sizes each profile for the source video according to its
+// fit mode, with even dimensions as the encoders require. The source size
+// is taken after rotation metadata is applied, as ffmpeg rotates frames
+// before filtering them. Profiles that would upscale the source are handled
+// according to the output's upscale policy; with "skip" the smallest profile
+// is still produced at the source scale if every profile would upscale, so
+// an output is never empty. Capped profiles that end up at the same
+// resolution as an earlier rendition are dropped. The names of dropped
+// profiles are returned alongside the ladder. If the source dimensions are
+// unknown the profiles keep their configured size.
+func resolveLadder(output *config.OutputConfig, profiles []config.ProfileConfig, inputInfo *VideoInfo) ([]config.ProfileConfig, []string) {
+	// Resolve fit modes and codecs on a copy, the profiles belong to the
+	// template
+	profiles = append([]config.ProfileConfig(nil), profiles...)
+	for i := range profiles {
+		profiles[i].Fit = profileFit(output, &profiles[i])
+		if profiles[i].Codec == "" {
+			profiles[i].Codec = output.Codec
+		}
+	}
+
+	srcWidth, srcHeight := inputInfo.displaySize()
+	if srcWidth <= 0 || srcHeight <= 0 {
+		return profiles, nil
+	}
+
+	policy := output.Upscale
+	if policy == "" {
+		policy = UpscaleSkip
+	}
+
+	var ladder []config.ProfileConfig
+	var skipped []string
+	produced := make(map[[2]int]bool)
+	for _, profile := range profiles {
+		width, height, scale := fitProfile(profile.Fit, srcWidth, srcHeight, profile.Width, profile.Height)
+		upscale := scale > 1
+		if upscale {
+			switch policy {
+			case UpscaleSkip:
+				skipped = append(skipped, profile.Name)
+				continue
+			case UpscaleCap:
+				width, height = capProfile(&profile, srcWidth, srcHeight, scale)
+			}
+		}
+
+		if upscale && policy == UpscaleCap && produced[[2]int{width, height}] {
+			skipped = append(skipped, profile.Name)
+			continue
+		}
+		produced[[2]int{width, height}] = true
+
+		profile.Width, profile.Height = width, height
+		ladder = append(ladder, profile)
+	}
+
+	if len(ladder) == 0 && len(profiles) > 0 {
+		smallest := profiles[0]
+		for _, profile := range profiles[1:] {
+			if profile.Width*profile.Height < smallest.Width*smallest.Height {
+				smallest = profile
+			}
+		}
+		_, _, scale := fitProfile(smallest.Fit, srcWidth, srcHeight, smallest.Width, smallest.Height)
+		smallest.Width, smallest.Height = capProfile(&smallest, srcWidth, srcHeight, scale)
+		ladder = append(ladder, smallest)
+
+		remaining := skipped[:0]
+		for _, name := range skipped {
+			if name != smallest.Name {
+				remaining = append(remaining, name)
+			}
+		}
+		skipped = remaining
+	}
+
+	if len(skipped) > 0 {
+		slog.Info("Skipped profiles above source resolution",
+			"outputName", output.Name,
+			"sourceResolution", fmt.Sprintf("%dx%d", srcWidth, srcHeight),
+			"upscale", policy,
+			"skipped", skipped,
+		)
+	}
+
+	return ladder, skipped
+}
+
+// profileFit returns a profile's fit mode: its own, else the output's, else
+// FitSource. Modes that need a box dimension the profile does not set fall
+// back to FitSource.
+func profileFit(output *config.OutputConfig, profile *config.ProfileConfig) string {
+	fit := profile.Fit
+	if fit == "" {
+		fit = output.Fit
+	}
+
+	switch fit {
+	case FitWidth:
+		if profile.Width > 0 {
+			return fit
+		}
+	case FitContain, FitCover:
+		if profile.Width > 0 && profile.Height > 0 {
+			return fit
+		}
+	}
+	return FitSource
+}
+
+// fitProfile returns the frame size of a rendition of a srcWidth x
+// srcHeight source in a boxWidth x boxHeight profile, and the factor the
+// source is scaled by. A zero box dimension is unconstrained.
+func fitProfile(fit string, srcWidth, srcHeight, boxWidth, boxHeight int) (int, int, float64) {
+	widthScale := float64(boxWidth) / float64(srcWidth)
+	heightScale := float64(boxHeight) / float64(srcHeight)
+
+	switch fit {
+	case FitWidth:
+		return fitWithin(srcWidth, srcHeight, boxWidth, 0)
+	case FitContain:
+		return evenDimension(float64(boxWidth)), evenDimension(float64(boxHeight)), math.Min(widthScale, heightScale)
+	case FitCover:
+		return evenDimension(float64(boxWidth)), evenDimension(float64(boxHeight)), math.Max(widthScale, heightScale)
+	default:
+		return fitWithin(srcWidth, srcHeight, boxWidth, boxHeight)
+	}
+}
+
+// capProfile returns the frame size of a profile whose source would be
+// scaled by scale > 1 when it is instead kept at the source scale: padded
+// and cropped frames shrink with it, other frames become the source size
+func capProfile(profile *config.ProfileConfig, srcWidth, srcHeight int, scale float64) (int, int) {
+	switch profile.Fit {
+	case FitContain, FitCover:
+		if scale > 1 {
+			return evenDimension(float64(profile.Width) / scale), evenDimension(float64(profile.Height) / scale)
+		}
+		return evenDimension(float64(profile.Width)), evenDimension(float64(profile.Height))
+	default:
+		return evenDimension(float64(srcWidth)), evenDimension(float64(srcHeight))
+	}
+}
+
+// fitWithin scales a source of srcWidth x srcHeight to fit inside a box of
+// boxWidth x boxHeight, preserving its aspect ratio. A zero box dimension is
+// unconstrained. It returns the scaled size and the scale factor.
+func fitWithin(srcWidth, srcHeight, boxWidth, boxHeight int) (int, int, float64) {
+	scale := math.Inf(1)
+	if boxWidth > 0 {
+		scale = float64(boxWidth) / float64(srcWidth)
+	}
+	if boxHeight > 0 {
+		scale = math.Min(scale, float64(boxHeight)/float64(srcHeight))
+	}
+	if math.IsInf(scale, 1) {
+		scale = 1
+	}
+
+	width := evenDimension(float64(srcWidth) * scale)
+	height := evenDimension(float64(srcHeight) * scale)
+	return width, height, scale
+}
+
+// evenDimension rounds a dimension to the nearest even number of at least 2
+func evenDimension(size float64) int {
+	even := int(math.Round(size/2)) * 2
+	if even < 2 {
+		return 2
+	}
+	return even
+}
+
+// videoFilter returns the ffmpeg filter chain that sizes a resolved profile.
+// Resolved frame sizes already follow the source aspect ratio; when the
+// source dimensions are unknown the profile was not resolved, so ffmpeg fits
+// the video to the profile's box itself.
+func videoFilter(profile *config.ProfileConfig, inputInfo *VideoInfo) string {
+	switch profile.Fit {
+	case FitContain:
+		return fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2,"+
+			"pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+			profile.Width, profile.Height, profile.Width, profile.Height)
+	case FitCover:
+		return fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1",
+			profile.Width, profile.Height, profile.Width, profile.Height)
+	}
+
+	if width, height := inputInfo.displaySize(); width <= 0 || height <= 0 {
+		if profile.Fit == FitWidth || profile.Height <= 0 {
+			return fmt.Sprintf("scale=%d:-2", profile.Width)
+		}
+		return fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2",
+			profile.Width, profile.Height)
+	}
+	return fmt.Sprintf("scale=%d:%d", profile.Width, profile.Height)
+}
+
+// splitFilter returns a filter graph that decodes the source once and sizes
+// a copy of the frames for each rendition, labelled [v0], [v1] and so on
+func splitFilter(ladder []config.ProfileConfig, inputInfo *VideoInfo) string {
+	var filter strings.Builder
+	fmt.Fprintf(&filter, "[0:v]split=%d", len(ladder))
+	for i := range ladder {
+		fmt.Fprintf(&filter, "[s%d]", i)
+	}
+	for i := range ladder {
+		fmt.Fprintf(&filter, ";[s%d]%s[v%d]", i, videoFilter(&ladder[i], inputInfo), i)
+	}
+	return filter.String()
+}
@@ -0,0 +1,181 @@
+package transcoder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestResolveLadder(t *testing.T) {
+	profiles := []config.ProfileConfig{
+		{Name: "360p", Width: 640, Height: 360},
+		{Name: "720p", Width: 1280, Height: 720},
+		{Name: "1080p", Width: 1920, Height: 1080},
+		{Name: "4k", Width: 3840, Height: 2160},
+	}
+
+	tests := []struct {
+		name     string
+		upscale  string
+		source   VideoInfo
+		fit      string
+		expected []string // name:widthxheight
+		skipped  []string
+	}{
+		{
+			name:     "skip profiles above a 720p source",
+			source:   VideoInfo{Width: 1280, Height: 720},
+			expected: []string{"360p:640x360", "720p:1280x720"},
+			skipped:  []string{"1080p", "4k"},
+		},
+		{
+			name:     "cap to the source and drop duplicates",
+			upscale:  UpscaleCap,
+			source:   VideoInfo{Width: 1280, Height: 720},
+			expected: []string{"360p:640x360", "720p:1280x720"},
+			skipped:  []string{"1080p", "4k"},
+		},
+		{
+			name:     "cap a source between rungs",
+			upscale:  UpscaleCap,
+			source:   VideoInfo{Width: 1600, Height: 900},
+			expected: []string{"360p:640x360", "720p:1280x720", "1080p:1600x900"},
+			skipped:  []string{"4k"},
+		},
+		{
+			name:     "allow upscaling",
+			upscale:  UpscaleAllow,
+			source:   VideoInfo{Width: 640, Height: 360},
+			expected: []string{"360p:640x360", "720p:1280x720", "1080p:1920x1080", "4k:3840x2160"},
+		},
+		{
+			name:     "preserve a 4:3 aspect ratio",
+			source:   VideoInfo{Width: 1440, Height: 1080},
+			expected: []string{"360p:480x360", "720p:960x720", "1080p:1440x1080"},
+			skipped:  []string{"4k"},
+		},
+		{
+			name:     "keep the smallest profile for a tiny source",
+			source:   VideoInfo{Width: 320, Height: 180},
+			expected: []string{"360p:320x180"},
+			skipped:  []string{"720p", "1080p", "4k"},
+		},
+		{
+			name:     "rotated portrait phone video",
+			source:   VideoInfo{Width: 1920, Height: 1080, Rotation: 90},
+			expected: []string{"360p:202x360", "720p:406x720", "1080p:608x1080"},
+			skipped:  []string{"4k"},
+		},
+		{
+			name:     "width-only fit of a portrait source",
+			fit:      FitWidth,
+			source:   VideoInfo{Width: 1080, Height: 1920},
+			expected: []string{"360p:640x1138"},
+			skipped:  []string{"720p", "1080p", "4k"},
+		},
+		{
+			name:     "letterbox a portrait source",
+			fit:      FitContain,
+			source:   VideoInfo{Width: 1080, Height: 1920},
+			expected: []string{"360p:640x360", "720p:1280x720", "1080p:1920x1080"},
+			skipped:  []string{"4k"},
+		},
+		{
+			name:     "crop a 4:3 source",
+			fit:      FitCover,
+			source:   VideoInfo{Width: 1440, Height: 1080},
+			expected: []string{"360p:640x360", "720p:1280x720"},
+			skipped:  []string{"1080p", "4k"},
+		},
+		{
+			name:     "cap a cropped profile at the source scale",
+			fit:      FitCover,
+			upscale:  UpscaleCap,
+			source:   VideoInfo{Width: 960, Height: 720},
+			expected: []string{"360p:640x360", "720p:960x540"},
+			skipped:  []string{"1080p", "4k"},
+		},
+		{
+			name:     "unknown source dimensions",
+			source:   VideoInfo{},
+			expected: []string{"360p:640x360", "720p:1280x720", "1080p:1920x1080", "4k:3840x2160"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := &config.OutputConfig{Name: "hls", Upscale: test.upscale, Fit: test.fit}
+			ladder, skipped := resolveLadder(output, profiles, &test.source)
+
+			var got []string
+			for _, profile := range ladder {
+				got = append(got, fmt.Sprintf("%s:%dx%d", profile.Name, profile.Width, profile.Height))
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected ladder %v, got %v", test.expected, got)
+			}
+			if !reflect.DeepEqual(skipped, test.skipped) {
+				t.Errorf("Expected skipped %v, got %v", test.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestCreateMasterPlaylist_ProducedRenditions(t *testing.T) {
+	transcoder := &Transcoder{}
+	output := &config.OutputConfig{Name: "hls"}
+	ladder, _ := resolveLadder(output, []config.ProfileConfig{
+		{Name: "480p", Width: 854, Height: 480, VideoBitrateKbps: 1200, AudioBitrateKbps: 128},
+		{Name: "1080p", Width: 1920, Height: 1080, VideoBitrateKbps: 5000, AudioBitrateKbps: 128},
+	}, &VideoInfo{Width: 480, Height: 854})
+
+	playlist, err := transcoder.createMasterPlaylist(ladder, &VideoInfo{}, hlsAudio{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create master playlist: %v", err)
+	}
+
+	if !strings.Contains(playlist, "RESOLUTION=270x480") {
+		t.Errorf("Expected portrait 480p rendition in master playlist, got:\n%s", playlist)
+	}
+	if strings.Contains(playlist, "1080p") {
+		t.Errorf("Expected skipped 1080p rendition to be left out of master playlist, got:\n%s", playlist)
+	}
+}
+
+func TestVideoFilter(t *testing.T) {
+	known := &VideoInfo{Width: 1920, Height: 1080}
+	unknown := &VideoInfo{}
+
+	tests := []struct {
+		profile   config.ProfileConfig
+		inputInfo *VideoInfo
+		expected  string
+	}{
+		{config.ProfileConfig{Width: 1280, Height: 720, Fit: FitSource}, known, "scale=1280:720"},
+		{config.ProfileConfig{Width: 1280, Height: 720, Fit: FitSource}, unknown,
+			"scale=w=1280:h=720:force_original_aspect_ratio=decrease:force_divisible_by=2"},
+		{config.ProfileConfig{Width: 1280, Height: 720, Fit: FitWidth}, unknown, "scale=1280:-2"},
+		{config.ProfileConfig{Width: 1280, Height: 720, Fit: FitContain}, known,
+			"scale=w=1280:h=720:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=1280:720:(ow-iw)/2:(oh-ih)/2,setsar=1"},
+		{config.ProfileConfig{Width: 1280, Height: 720, Fit: FitCover}, known,
+			"scale=w=1280:h=720:force_original_aspect_ratio=increase,crop=1280:720,setsar=1"},
+	}
+
+	for _, test := range tests {
+		if got := videoFilter(&test.profile, test.inputInfo); got != test.expected {
+			t.Errorf("videoFilter(%s) = %q, expected %q", test.profile.Fit, got, test.expected)
+		}
+	}
+}
+
+func TestNormalizeRotation(t *testing.T) {
+	tests := map[float64]int{0: 0, 90: 90, -90: 270, 180: 180, -180: 180, 270: 270, 360: 0, 89.99: 90}
+	for degrees, expected := range tests {
+		if got := normalizeRotation(degrees); got != expected {
+			t.Errorf("normalizeRotation(%v) = %d, expected %d", degrees, got, expected)
+		}
+	}
+}
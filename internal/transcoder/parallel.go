@@ -0,0 +1,152 @@
+package transcoder
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// slotPool is a counting semaphore bounding concurrent ffmpeg encodes. A
+// nil pool does not limit.
+type slotPool chan struct{}
+
+// newSlotPool creates a pool of size slots
+func newSlotPool(size int) slotPool {
+	if size < 1 {
+		size = 1
+	}
+	return make(slotPool, size)
+}
+
+// acquire blocks until a slot is free or ctx is done
+func (p slotPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// release frees a slot taken by acquire
+func (p slotPool) release() {
+	if p != nil {
+		<-p
+	}
+}
+
+// jobSlotsKey is the context key of a job's encode limit
+type jobSlotsKey struct{}
+
+// withJobSlots limits the encodes of the job running under ctx to the
+// pool's size. Each encode also takes a slot of the transcoder's shared
+// pool, so parallel jobs cannot oversubscribe the node.
+func withJobSlots(ctx context.Context, slots slotPool) context.Context {
+	return context.WithValue(ctx, jobSlotsKey{}, slots)
+}
+
+// acquireEncodeSlot takes a slot of the job's limit and of the shared pool;
+// the returned function releases both
+func (t *Transcoder) acquireEncodeSlot(ctx context.Context) (func(), error) {
+	jobSlots, _ := ctx.Value(jobSlotsKey{}).(slotPool)
+	if err := jobSlots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := t.slots.acquire(ctx); err != nil {
+		jobSlots.release()
+		return nil, err
+	}
+	return func() {
+		t.slots.release()
+		jobSlots.release()
+	}, nil
+}
+
+// forEach calls fn for the indexes 0 to n-1, one after another or, when
+// parallel is set, concurrently. Once a call fails the context of the other
+// calls is cancelled; the first error is returned after all calls return.
+func forEach(ctx context.Context, n int, parallel bool, fn func(ctx context.Context, i int) error) error {
+	if !parallel || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// progressStages combines the progress of equal parts of the work, such as
+// the outputs of a job or the renditions of an output, which may run one
+// after another or concurrently. The combined progress keeps rising instead
+// of restarting at 0 for each part.
+type progressStages struct {
+	mu       sync.Mutex
+	callback ProgressCallback
+	progress []float64
+}
+
+// newProgressStages splits the progress reported to callback into stages
+func newProgressStages(callback ProgressCallback, stages int) *progressStages {
+	return &progressStages{
+		callback: callback,
+		progress: make([]float64, stages),
+	}
+}
+
+// stage returns the callback reporting the progress of stage i
+func (p *progressStages) stage(i int) ProgressCallback {
+	if p.callback == nil {
+		return nil
+	}
+	if len(p.progress) <= 1 {
+		return p.callback
+	}
+	return func(progress float64, currentFrame, totalFrames int, speed float64) {
+		p.report(i, progress, currentFrame, totalFrames, speed)
+	}
+}
+
+// complete marks stage i as done; it counts as such from the next report
+func (p *progressStages) complete(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress[i] = 1
+}
+
+// report records the progress of stage i and reports the combined progress
+func (p *progressStages) report(i int, progress float64, currentFrame, totalFrames int, speed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.progress[i] = math.Max(p.progress[i], math.Min(progress, 1))
+	var total float64
+	for _, stageProgress := range p.progress {
+		total += stageProgress
+	}
+	p.callback(total/float64(len(p.progress)), currentFrame, totalFrames, speed)
+}
@@ -0,0 +1,111 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProgressStages(t *testing.T) {
+	var reported []float64
+	callback := func(progress float64, currentFrame, totalFrames int, speed float64) {
+		reported = append(reported, progress)
+	}
+
+	// Third of four renditions of the second of two outputs
+	outputs := newProgressStages(callback, 2)
+	outputs.complete(0)
+	renditions := newProgressStages(outputs.stage(1), 4)
+	renditions.complete(0)
+	renditions.complete(1)
+	stage := renditions.stage(2)
+	stage(0, 0, 100, 1)
+	stage(0.5, 50, 100, 1)
+	stage(1.5, 150, 100, 1)
+
+	// A rendition running alongside reports its own share
+	renditions.stage(3)(0.5, 50, 100, 1)
+
+	expected := []float64{0.75, 0.8125, 0.875, 0.9375}
+	if len(reported) != len(expected) {
+		t.Fatalf("Expected %d reports, got %v", len(expected), reported)
+	}
+	for i, progress := range expected {
+		if abs(reported[i]-progress) > 0.0001 {
+			t.Errorf("Expected progress %f, got %f", progress, reported[i])
+		}
+	}
+
+	if newProgressStages(nil, 2).stage(0) != nil {
+		t.Error("Expected nil callback to stay nil")
+	}
+}
+
+func TestForEach_LimitsEncodes(t *testing.T) {
+	transcoder := &Transcoder{slots: newSlotPool(3)}
+	ctx := withJobSlots(context.Background(), newSlotPool(2))
+
+	var running, maxRunning atomic.Int32
+	var mu sync.Mutex
+	var done []int
+	err := forEach(ctx, 6, true, func(ctx context.Context, i int) error {
+		release, err := transcoder.acquireEncodeSlot(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		current := running.Add(1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+
+		mu.Lock()
+		done = append(done, i)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(done) != 6 {
+		t.Errorf("Expected 6 encodes, got %d", len(done))
+	}
+	if maxRunning.Load() != 2 {
+		t.Errorf("Expected the job limit of 2 concurrent encodes, got %d", maxRunning.Load())
+	}
+}
+
+func TestForEach_FirstErrorCancels(t *testing.T) {
+	failure := errors.New("encode failed")
+
+	err := forEach(context.Background(), 3, true, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return failure
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the first error, got %v", err)
+	}
+
+	// Sequential calls stop at the first error
+	calls := 0
+	err = forEach(context.Background(), 3, false, func(ctx context.Context, i int) error {
+		calls++
+		return failure
+	})
+	if !errors.Is(err, failure) || calls != 1 {
+		t.Errorf("Expected one call returning the error, got %d calls and %v", calls, err)
+	}
+}
@@ -16,7 +16,7 @@ import (
 // transcodeProgressive performs progressive MP4 transcoding
 func (t *Transcoder) transcodeProgressive(ctx context.Context, inputPath string,
 	output *config.OutputConfig, outputDir string, inputInfo *VideoInfo,
-	ffmpegConfig config.JobFFmpegConfig, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
+	ffmpegConfig config.JobFFmpegConfig, parallel bool, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
 
 	startTime := time.Now()
 	slog.Info("Starting progressive MP4 transcoding",
@@ -25,45 +25,44 @@ func (t *Transcoder) transcodeProgressive(ctx context.Context, inputPath string,
 		"profiles", len(output.Profiles),
 	)
 
+	profiles := t.outputProfiles(output)
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles specified for progressive output")
+	}
+	ladder, skipped := resolveLadder(output, profiles, inputInfo)
+
 	var files []models.OutputFile
 	var totalFrames int
 
-	// If we have multiple profiles, create one MP4 file per profile
-	if len(output.Profiles) > 0 {
-		for i, profile := range output.Profiles {
-			slog.Info("Transcoding progressive MP4 profile",
-				"profile", profile.Name,
-				"resolution", fmt.Sprintf("%dx%d", profile.Width, profile.Height),
-				"bitrate", profile.VideoBitrateKbps,
-			)
-
-			profileFile, frames, err := t.transcodeProgressiveProfile(ctx, inputPath, &profile,
-				outputDir, inputInfo, output, ffmpegConfig, progressCallback)
-			if err != nil {
-				return nil, fmt.Errorf("failed to transcode progressive profile '%s': %w", profile.Name, err)
-			}
-
-			files = append(files, *profileFile)
-			if i == 0 { // Use first profile for total frame count
-				totalFrames = frames
-			}
-		}
-	} else if output.Profile != "" {
-		// Single profile output
-		slog.Info("Transcoding single progressive MP4 profile", "profile", output.Profile)
-
-		profile := t.getProfileByName(output.Profile)
+	// Create one MP4 file per rendition
+	renditionFiles := make([]*models.OutputFile, len(ladder))
+	progress := newProgressStages(progressCallback, len(ladder))
+	err := forEach(ctx, len(ladder), parallel, func(ctx context.Context, i int) error {
+		profile := ladder[i]
+		slog.Info("Transcoding progressive MP4 profile",
+			"profile", profile.Name,
+			"resolution", fmt.Sprintf("%dx%d", profile.Width, profile.Height),
+			"bitrate", profile.VideoBitrateKbps,
+		)
 
 		profileFile, frames, err := t.transcodeProgressiveProfile(ctx, inputPath, &profile,
-			outputDir, inputInfo, output, ffmpegConfig, progressCallback)
+			outputDir, inputInfo, output, ffmpegConfig, progress.stage(i))
 		if err != nil {
-			return nil, fmt.Errorf("failed to transcode progressive profile '%s': %w", output.Profile, err)
+			return fmt.Errorf("failed to transcode progressive profile '%s': %w", profile.Name, err)
 		}
+		progress.complete(i)
 
+		renditionFiles[i] = profileFile
+		if i == 0 { // Use first profile for total frame count
+			totalFrames = frames
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, profileFile := range renditionFiles {
 		files = append(files, *profileFile)
-		totalFrames = frames
-	} else {
-		return nil, fmt.Errorf("no profiles specified for progressive output")
 	}
 
 	result := &models.ConversionOutput{
@@ -78,6 +77,12 @@ func (t *Transcoder) transcodeProgressive(ctx context.Context, inputPath string,
 			"processing_time": time.Since(startTime).String(),
 		},
 	}
+	if len(skipped) > 0 {
+		result.Metadata["skipped_profiles"] = strings.Join(skipped, ",")
+	}
+	if tracks := selectAudioTracks(output, inputInfo); len(tracks) > 0 {
+		result.Metadata["audio_tracks"] = audioTrackNames(tracks)
+	}
 
 	slog.Info("Progressive MP4 transcoding completed",
 		"outputName", output.Name,
@@ -95,26 +100,28 @@ func (t *Transcoder) transcodeProgressiveProfile(ctx context.Context, inputPath
 	progressCallback ProgressCallback) (*models.OutputFile, int, error) {
 
 	// Determine container format
-	container := output.Container
-	if container == "" {
-		container = "mp4"
-	}
+	container := progressiveContainer(output)
 
 	// Create output filename
 	outputFileName := fmt.Sprintf("%s.%s", profile.Name, container)
 	outputPath := filepath.Join(outputDir, outputFileName)
 
-	// Build FFmpeg command for progressive output
-	args := t.buildProgressiveFFmpegArgs(inputPath, outputPath, profile, ffmpegConfig)
-
-	slog.Debug("Running FFmpeg for progressive MP4",
-		"profile", profile.Name,
-		"outputPath", outputPath,
-		"args", strings.Join(args, " "),
-	)
-
-	// Run FFmpeg with progress monitoring
-	if err := t.runFFmpegWithProgress(ctx, args, inputInfo.TotalFrames, progressCallback); err != nil {
+	tracks := selectAudioTracks(output, inputInfo)
+
+	// Run FFmpeg with progress monitoring, twice for two-pass encodes
+	passes := encodePasses([]config.ProfileConfig{*profile}, passLogFile(outputDir, profile.Name))
+	err := t.runEncodePasses(ctx, passes, func(pass encodePass) []string {
+		args := t.buildProgressiveFFmpegArgs(inputPath, outputPath, container, profile, inputInfo, tracks,
+			ffmpegConfig, pass)
+		slog.Debug("Running FFmpeg for progressive MP4",
+			"profile", profile.Name,
+			"outputPath", outputPath,
+			"pass", pass.number,
+			"args", strings.Join(args, " "),
+		)
+		return args
+	}, inputInfo.TotalFrames, progressCallback)
+	if err != nil {
 		return nil, 0, fmt.Errorf("ffmpeg execution failed: %w", err)
 	}
 
@@ -123,19 +130,18 @@ func (t *Transcoder) transcodeProgressiveProfile(ctx context.Context, inputPath
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create output file info: %w", err)
 	}
+	outputFile.Profile = profile.Name
 
 	return outputFile, inputInfo.TotalFrames, nil
 }
 
-// buildProgressiveFFmpegArgs builds FFmpeg arguments for progressive MP4 transcoding
-func (t *Transcoder) buildProgressiveFFmpegArgs(inputPath, outputPath string,
-	profile *config.ProfileConfig, ffmpegConfig config.JobFFmpegConfig) []string {
+// buildProgressiveFFmpegArgs builds FFmpeg arguments for one pass of
+// progressive MP4 transcoding
+func (t *Transcoder) buildProgressiveFFmpegArgs(inputPath, outputPath, container string,
+	profile *config.ProfileConfig, inputInfo *VideoInfo, tracks []audioTrack, ffmpegConfig config.JobFFmpegConfig,
+	pass encodePass) []string {
 
-	args := []string{
-		"-i", inputPath,
-		"-c:v", "libx264",
-		"-c:a", "aac",
-	}
+	args := []string{"-i", inputPath, "-map", "0:v:0"}
 
 	// Add hardware acceleration if configured
 	if ffmpegConfig.HWAccel != "" {
@@ -143,33 +149,31 @@ func (t *Transcoder) buildProgressiveFFmpegArgs(inputPath, outputPath string,
 	}
 
 	// Video encoding settings
-	args = append(args,
-		"-vf", fmt.Sprintf("scale=%d:%d", profile.Width, profile.Height),
-		"-b:v", fmt.Sprintf("%dk", profile.VideoBitrateKbps),
-		"-maxrate", fmt.Sprintf("%dk", profile.VideoBitrateKbps),
-		"-bufsize", fmt.Sprintf("%dk", profile.VideoBitrateKbps*2),
-		"-profile:v", "high",
-		"-level", "4.0",
-	)
+	args = append(args, "-vf", videoFilter(profile, inputInfo))
+	args = append(args, videoStreamArgs(profile, inputInfo, "", 0, ffmpegConfig.Preset, pass)...)
+	if pass.analysis() {
+		return append(args, analysisOutputArgs()...)
+	}
 
-	// Audio encoding settings
-	if profile.AudioBitrateKbps > 0 {
-		args = append(args,
-			"-b:a", fmt.Sprintf("%dk", profile.AudioBitrateKbps),
-		)
+	// Audio encoding settings; every selected track is kept in the file
+	if len(tracks) > 0 {
+		audioEncoder, _ := audioCodecFor(container)
+		args = append(args, audioMapArgs(tracks, 0)...)
+		args = append(args, "-c:a", audioEncoder)
+		if profile.AudioBitrateKbps > 0 {
+			args = append(args,
+				"-b:a", fmt.Sprintf("%dk", profile.AudioBitrateKbps),
+			)
+		} else {
+			args = append(args, "-b:a", "128k")
+		}
 	} else {
-		args = append(args, "-b:a", "128k")
+		args = append(args, "-an")
 	}
 
 	// Progressive download optimization
-	args = append(args,
-		"-movflags", "+faststart", // Move moov atom to beginning for progressive download
-		"-pix_fmt", "yuv420p", // Ensure compatibility
-	)
-
-	// Add preset if configured
-	if ffmpegConfig.Preset != "" {
-		args = append(args, "-preset", ffmpegConfig.Preset)
+	if container == "mp4" || container == "mov" {
+		args = append(args, "-movflags", "+faststart") // Move moov atom to beginning for progressive download
 	}
 
 	// Add extra args if configured
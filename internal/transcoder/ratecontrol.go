@@ -0,0 +1,210 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+// Rate control modes of a profile
+const (
+	RateBitrate   = "bitrate"    // Target video_bitrate_kbps, capped at it (default)
+	RateCRF       = "crf"        // Constant quality, any bitrate
+	RateCappedCRF = "capped_crf" // Constant quality up to max_bitrate_kbps
+	RateTwoPass   = "two_pass"   // Average video_bitrate_kbps spread by an analysis pass
+	RateCBR       = "cbr"        // Constant video_bitrate_kbps with HRD signalling
+)
+
+// encodePass is one ffmpeg run of an encode. A two-pass encode runs an
+// analysis pass that only writes statistics to the pass log, then the
+// encode proper, which reads them.
+type encodePass struct {
+	number  int    // 1 or 2 for two-pass encodes, 0 otherwise
+	logFile string // Pass log prefix
+}
+
+// analysis reports whether the pass is the analysis pass of a two-pass encode
+func (p encodePass) analysis() bool {
+	return p.number == 1
+}
+
+// profileRateControl returns a profile's rate control mode
+func profileRateControl(profile *config.ProfileConfig) string {
+	if profile.RateControl == "" {
+		return RateBitrate
+	}
+	return profile.RateControl
+}
+
+// validateRateControl checks that a profile's codec supports its rate
+// control mode and CRF
+func validateRateControl(codec *videoCodec, profile *config.ProfileConfig) error {
+	mode := profileRateControl(profile)
+	if (mode == RateCRF || mode == RateCappedCRF) && profile.CRF != nil && *profile.CRF > codec.maxCRF {
+		return fmt.Errorf("crf %d is above the %s maximum of %d", *profile.CRF, codec.encoder, codec.maxCRF)
+	}
+	if (codec.encoder == "libx264" || codec.encoder == "libsvtav1") &&
+		(mode == RateCRF || mode == RateCappedCRF) && profile.CRF != nil && *profile.CRF == 0 {
+		// crf 0 makes x264 encode lossless, which the High and Main
+		// profiles it is pinned to refuse; libsvtav1 reads it as unset and
+		// falls back to its default
+		return fmt.Errorf("crf 0 is not supported by %s", codec.encoder)
+	}
+	if codec.encoder == "libsvtav1" && (mode == RateTwoPass || mode == RateCBR) {
+		return fmt.Errorf("rate control %s is not supported by libsvtav1", mode)
+	}
+	return nil
+}
+
+// peakVideoBitrate returns the highest video bitrate, in kbps, a profile's
+// rate control lets through: max_bitrate_kbps for capped CRF and two-pass
+// encodes that set it, else video_bitrate_kbps. Constant quality encodes
+// have no cap and return 0.
+func peakVideoBitrate(profile *config.ProfileConfig) int {
+	switch profileRateControl(profile) {
+	case RateCRF:
+		return 0
+	case RateCappedCRF, RateTwoPass:
+		if profile.MaxBitrateKbps > 0 {
+			return profile.MaxBitrateKbps
+		}
+	}
+	return profile.VideoBitrateKbps
+}
+
+// rateArgs adds the rate control options of a video stream
+func (c *videoCodec) rateArgs(s *streamArgs, profile *config.ProfileConfig, pass encodePass) {
+	kbps := func(rate int) string { return fmt.Sprintf("%dk", rate) }
+	bitrate := profile.VideoBitrateKbps
+
+	crf := c.defaultCRF
+	if profile.CRF != nil {
+		crf = *profile.CRF
+	}
+
+	// libvpx and libaom take the cap of a constrained quality encode as
+	// the target bitrate, and need a zero target for constant quality
+	constrainedQuality := c.encoder == "libvpx-vp9" || c.encoder == "libaom-av1"
+
+	switch profileRateControl(profile) {
+	case RateCRF:
+		s.add("-crf", strconv.Itoa(crf))
+		if constrainedQuality {
+			s.add("-b", "0")
+		}
+	case RateCappedCRF:
+		maxBitrate := profile.MaxBitrateKbps
+		if maxBitrate <= 0 {
+			maxBitrate = bitrate
+		}
+		s.add("-crf", strconv.Itoa(crf))
+		if constrainedQuality {
+			s.add("-b", kbps(maxBitrate))
+			break
+		}
+		s.add("-maxrate", kbps(maxBitrate))
+		if c.encoder != "libsvtav1" {
+			s.add("-bufsize", kbps(maxBitrate*2))
+		}
+	case RateTwoPass:
+		s.add("-b", kbps(bitrate))
+		if profile.MaxBitrateKbps > 0 {
+			s.add("-maxrate", kbps(profile.MaxBitrateKbps))
+			s.add("-bufsize", kbps(profile.MaxBitrateKbps*2))
+		}
+		if c.encoder == "libx265" {
+			// x265 keeps its own statistics file, one per stream
+			stats := fmt.Sprintf("%s-x265%s.log", pass.logFile, strings.ReplaceAll(s.stream, ":", "-"))
+			s.x265Params = append(s.x265Params, fmt.Sprintf("pass=%d", pass.number), "stats="+stats)
+		} else {
+			s.add("-pass", strconv.Itoa(pass.number))
+			s.add("-passlogfile", pass.logFile)
+		}
+	case RateCBR:
+		// A one second buffer keeps the rate constant over short windows
+		s.add("-b", kbps(bitrate))
+		s.add("-minrate", kbps(bitrate))
+		s.add("-maxrate", kbps(bitrate))
+		s.add("-bufsize", kbps(bitrate))
+		switch c.encoder {
+		case "libx264":
+			s.x264Params = append(s.x264Params, "nal-hrd=cbr")
+		case "libx265":
+			s.x265Params = append(s.x265Params, "strict-cbr=1")
+		}
+	default:
+		s.add("-b", kbps(bitrate))
+		if c.encoder == "libsvtav1" {
+			// SVT-AV1 switches to CBR when the cap equals the target
+			break
+		}
+		s.add("-maxrate", kbps(bitrate))
+		s.add("-bufsize", kbps(bitrate*2))
+	}
+}
+
+// encodePasses returns the passes of an encode of the profiles: two if any
+// uses two-pass rate control, with pass logs named after logFile, else one
+func encodePasses(profiles []config.ProfileConfig, logFile string) []encodePass {
+	for i := range profiles {
+		if profileRateControl(&profiles[i]) == RateTwoPass {
+			return []encodePass{{number: 1, logFile: logFile}, {number: 2, logFile: logFile}}
+		}
+	}
+	return []encodePass{{}}
+}
+
+// passLogFile returns the pass log prefix of an encode writing to
+// outputDir. Logs go to the job temp directory, the parent of outputDir,
+// so they are never collected as output files.
+func passLogFile(outputDir, name string) string {
+	return filepath.Join(filepath.Dir(outputDir), fmt.Sprintf("passlog-%s-%s", filepath.Base(outputDir), name))
+}
+
+// analysisOutputArgs returns the output options of an analysis pass, which
+// discards the encoded video and skips audio
+func analysisOutputArgs() []string {
+	return []string{"-an", "-f", "null", os.DevNull}
+}
+
+// runEncodePasses runs the passes of an encode, with buildArgs returning
+// the ffmpeg arguments of each pass. Progress is split between the passes.
+// The pass logs of a two-pass encode are removed once it ends.
+func (t *Transcoder) runEncodePasses(ctx context.Context, passes []encodePass, buildArgs func(pass encodePass) []string,
+	totalFrames int, progressCallback ProgressCallback) error {
+
+	if len(passes) > 1 {
+		defer removePassLogs(passes[0].logFile)
+	}
+
+	progress := newProgressStages(progressCallback, len(passes))
+	for i, pass := range passes {
+		if err := t.runFFmpegWithProgress(ctx, buildArgs(pass), totalFrames, progress.stage(i)); err != nil {
+			if pass.analysis() {
+				return fmt.Errorf("analysis pass failed: %w", err)
+			}
+			return err
+		}
+		progress.complete(i)
+	}
+	return nil
+}
+
+// removePassLogs deletes the pass logs starting with logFile
+func removePassLogs(logFile string) {
+	logs, err := filepath.Glob(logFile + "*")
+	if err != nil {
+		return
+	}
+	for _, log := range logs {
+		if err := os.Remove(log); err != nil {
+			slog.Warn("Failed to remove pass log", "path", log, "error", err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package transcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+// crf returns a profile CRF setting
+func crf(value int) *int {
+	return &value
+}
+
+func TestRateArgs(t *testing.T) {
+	pass := encodePass{number: 2, logFile: "/tmp/job/passlog-hls-720p"}
+
+	tests := []struct {
+		name     string
+		codec    string
+		profile  config.ProfileConfig
+		pass     encodePass
+		expected string
+	}{
+		{"default bitrate", "h264", config.ProfileConfig{VideoBitrateKbps: 2500},
+			encodePass{}, "-b:v 2500k -maxrate:v 2500k -bufsize:v 5000k"},
+		{"x264 crf default", "h264", config.ProfileConfig{RateControl: RateCRF},
+			encodePass{}, "-crf:v 23"},
+		{"vp9 crf", "vp9", config.ProfileConfig{RateControl: RateCRF, CRF: crf(33)},
+			encodePass{}, "-crf:v 33 -b:v 0"},
+		{"vp9 crf 0", "vp9", config.ProfileConfig{RateControl: RateCRF, CRF: crf(0)},
+			encodePass{}, "-crf:v 0 -b:v 0"},
+		{"x264 capped crf", "h264", config.ProfileConfig{RateControl: RateCappedCRF, CRF: crf(20), VideoBitrateKbps: 3000},
+			encodePass{}, "-crf:v 20 -maxrate:v 3000k -bufsize:v 6000k"},
+		{"vp9 capped crf", "vp9", config.ProfileConfig{RateControl: RateCappedCRF, MaxBitrateKbps: 4000, VideoBitrateKbps: 3000},
+			encodePass{}, "-crf:v 31 -b:v 4000k"},
+		{"svt-av1 capped crf", "av1", config.ProfileConfig{RateControl: RateCappedCRF, VideoBitrateKbps: 3000},
+			encodePass{}, "-crf:v 35 -maxrate:v 3000k"},
+		{"x264 two-pass", "h264", config.ProfileConfig{RateControl: RateTwoPass, VideoBitrateKbps: 2500},
+			pass, "-b:v 2500k -pass:v 2 -passlogfile:v /tmp/job/passlog-hls-720p"},
+		{"x265 two-pass", "hevc", config.ProfileConfig{RateControl: RateTwoPass, VideoBitrateKbps: 2500, MaxBitrateKbps: 4000},
+			pass, "-b:v 2500k -maxrate:v 4000k -bufsize:v 8000k -x265-params:v level-idc=4.0:pass=2:stats=/tmp/job/passlog-hls-720p-x265.log"},
+		{"x264 cbr", "h264", config.ProfileConfig{RateControl: RateCBR, VideoBitrateKbps: 6000},
+			encodePass{}, "-b:v 6000k -minrate:v 6000k -maxrate:v 6000k -bufsize:v 6000k -x264-params:v nal-hrd=cbr"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codec, err := lookupVideoCodec(test.codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.profile.Codec = test.codec
+			test.profile.Width, test.profile.Height = 1920, 1080
+
+			args := strings.Join(videoStreamArgs(&test.profile, &VideoInfo{FrameRate: 30}, "", 0, "", test.pass), " ")
+			if !strings.HasSuffix(args, test.expected) {
+				t.Errorf("Expected %s args to end with %q, got %s", codec.encoder, test.expected, args)
+			}
+		})
+	}
+}
+
+func TestValidateRateControl(t *testing.T) {
+	tests := []struct {
+		codec   string
+		profile config.ProfileConfig
+		valid   bool
+	}{
+		{"h264", config.ProfileConfig{RateControl: RateCRF, CRF: crf(51)}, true},
+		{"h264", config.ProfileConfig{RateControl: RateCRF, CRF: crf(52)}, false},
+		{"vp9", config.ProfileConfig{RateControl: RateCappedCRF, CRF: crf(63)}, true},
+		{"h264", config.ProfileConfig{RateControl: RateCRF, CRF: crf(0)}, false},
+		{"hevc", config.ProfileConfig{RateControl: RateCRF, CRF: crf(0)}, true},
+		{"av1", config.ProfileConfig{RateControl: RateCRF, CRF: crf(0)}, false},
+		{"libaom-av1", config.ProfileConfig{RateControl: RateTwoPass}, true},
+		{"av1", config.ProfileConfig{RateControl: RateTwoPass}, false},
+		{"av1", config.ProfileConfig{RateControl: RateCBR}, false},
+		{"hevc", config.ProfileConfig{RateControl: RateCBR}, true},
+	}
+
+	for i, test := range tests {
+		codec, _ := lookupVideoCodec(test.codec)
+		err := validateRateControl(codec, &test.profile)
+		if test.valid != (err == nil) {
+			t.Errorf("Test %d: validateRateControl(%s, %s) = %v, expected valid %v",
+				i, test.codec, test.profile.RateControl, err, test.valid)
+		}
+	}
+}
+
+func TestEncodePasses(t *testing.T) {
+	ladder := []config.ProfileConfig{{Name: "360p"}, {Name: "720p", RateControl: RateTwoPass}}
+
+	if passes := encodePasses(ladder[:1], "log"); !reflect.DeepEqual(passes, []encodePass{{}}) {
+		t.Errorf("Expected a single pass, got %v", passes)
+	}
+	expected := []encodePass{{number: 1, logFile: "log"}, {number: 2, logFile: "log"}}
+	if passes := encodePasses(ladder, "log"); !reflect.DeepEqual(passes, expected) {
+		t.Errorf("Expected two passes, got %v", passes)
+	}
+
+	if logFile := passLogFile("/tmp/job/hls", "720p"); logFile != "/tmp/job/passlog-hls-720p" {
+		t.Errorf("Expected pass log in the job temp directory, got %s", logFile)
+	}
+}
+
+func TestRunEncodePasses_RemovesPassLogs(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "passlog-hls-720p")
+	passes := []encodePass{{number: 1, logFile: logFile}, {number: 2, logFile: logFile}}
+
+	for _, binary := range []string{"true", "false"} {
+		for _, name := range []string{"-0.log", "-0.log.mbtree"} {
+			if err := os.WriteFile(logFile+name, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		transcoder := &Transcoder{ffmpegBin: binary}
+		var ran []int
+		err := transcoder.runEncodePasses(context.Background(), passes, func(pass encodePass) []string {
+			ran = append(ran, pass.number)
+			return nil
+		}, 0, nil)
+
+		if binary == "true" && (err != nil || !reflect.DeepEqual(ran, []int{1, 2})) {
+			t.Errorf("Expected both passes to run, got %v (err %v)", ran, err)
+		}
+		if binary == "false" && (err == nil || !strings.Contains(err.Error(), "analysis pass") || len(ran) != 1) {
+			t.Errorf("Expected the analysis pass to fail alone, got %v (err %v)", ran, err)
+		}
+		if logs, _ := filepath.Glob(logFile + "*"); len(logs) > 0 {
+			t.Errorf("Expected pass logs to be removed after %s, found %v", binary, logs)
+		}
+	}
+}
+
+func TestAnalysisPassArgs(t *testing.T) {
+	transcoder := &Transcoder{}
+	profile := config.ProfileConfig{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500,
+		RateControl: RateTwoPass, Fit: FitSource}
+	inputInfo := &VideoInfo{Width: 1920, Height: 1080, AudioCodec: "aac"}
+	output := &config.OutputConfig{Package: "hls"}
+	pass := encodePass{number: 1, logFile: "/tmp/job/passlog-hls-720p"}
+
+	for name, args := range map[string][]string{
+		"hls": transcoder.buildHLSFFmpegArgs("in.mp4", "/out/720p", &profile, inputInfo, output,
+			config.JobFFmpegConfig{}, pass),
+		"single-pass hls": transcoder.buildHLSSinglePassArgs("in.mp4", "/out", []config.ProfileConfig{profile},
+			inputInfo, output, config.JobFFmpegConfig{}, pass),
+		"dash": transcoder.buildDASHFFmpegArgs("in.mp4", "/out", []config.ProfileConfig{profile},
+			inputInfo, &config.OutputConfig{Package: "dash"}, config.JobFFmpegConfig{}, pass),
+	} {
+		joined := strings.Join(args, " ")
+		if !strings.Contains(joined, "-pass:v") || !strings.HasSuffix(joined, "-an -f null "+os.DevNull) {
+			t.Errorf("Expected %s analysis pass writing only the pass log, got %s", name, joined)
+		}
+		if strings.Contains(joined, "-c:a") || strings.Contains(joined, "-f hls") || strings.Contains(joined, "-f dash") {
+			t.Errorf("Expected no audio or packaging in %s analysis pass, got %s", name, joined)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// Subtitle track selections of an output
+const (
+	SubtitleTracksAll  = "all"  // Every sidecar and embedded text subtitle (default)
+	SubtitleTracksNone = "none" // No subtitles
+)
+
+// textSubtitleCodecs lists the subtitle codecs ffmpeg can convert to
+// WebVTT. Bitmap subtitles (PGS, VobSub, DVB) would need OCR and are skipped.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"mov_text": true,
+	"webvtt":   true,
+	"text":     true,
+}
+
+// SidecarCaption is a caption file attached to a job, downloaded next to
+// the source
+type SidecarCaption struct {
+	Path     string // Local file path
+	Language string // ISO 639-2 language tag, empty if unknown
+	Label    string // Display name, defaults to the language
+	Default  bool
+}
+
+// subtitleTrack is a sidecar caption file or embedded text subtitle stream
+// selected for an output
+type subtitleTrack struct {
+	inputPath string // Source or sidecar file
+	index     int    // Position among the input's subtitle streams, as in 0:s:N
+	language  string // ISO 639-2 language tag, "und" if untagged
+	name      string // Rendition name: subs_<language>, with a number for duplicate languages
+	label     string // Display name: the title or label, else the language
+	isDefault bool
+	forced    bool
+}
+
+// selectSubtitleTracks returns the subtitles an output carries: the job's
+// sidecar captions followed by the source's text subtitle streams. Language
+// tags in the output's subtitle_tracks select the first subtitles of each
+// language in the listed order, "none" selects nothing and "all", the
+// default, selects everything.
+func selectSubtitleTracks(output *config.OutputConfig, inputPath string, inputInfo *VideoInfo,
+	sidecars []SidecarCaption) []subtitleTrack {
+
+	var candidates []subtitleTrack
+	for _, sidecar := range sidecars {
+		candidates = append(candidates, subtitleTrack{
+			inputPath: sidecar.Path,
+			language:  sidecar.Language,
+			label:     sidecar.Label,
+			isDefault: sidecar.Default,
+		})
+	}
+	for i, stream := range inputInfo.SubtitleStreams {
+		if !textSubtitleCodecs[stream.Codec] {
+			slog.Info("Skipping bitmap subtitle stream",
+				"outputName", output.Name,
+				"stream", i,
+				"codec", stream.Codec,
+				"language", stream.Language,
+			)
+			continue
+		}
+		candidates = append(candidates, subtitleTrack{
+			inputPath: inputPath,
+			index:     i,
+			language:  stream.Language,
+			label:     stream.Title,
+			isDefault: stream.Default,
+			forced:    stream.Forced,
+		})
+	}
+
+	selected := candidates
+	if len(output.SubtitleTracks) > 0 {
+		selected = nil
+	}
+	for _, selection := range output.SubtitleTracks {
+		if strings.EqualFold(selection, SubtitleTracksNone) {
+			return nil
+		}
+		if strings.EqualFold(selection, SubtitleTracksAll) {
+			selected = candidates
+			break
+		}
+		for _, candidate := range candidates {
+			if strings.EqualFold(candidate.language, selection) {
+				selected = append(selected, candidate)
+				break
+			}
+		}
+	}
+
+	tracks := make([]subtitleTrack, 0, len(selected))
+	seen := make(map[string]bool)
+	hasDefault := false
+	for _, track := range selected {
+		track.language = strings.ToLower(track.language)
+		if track.language == "" {
+			track.language = "und"
+		}
+
+		track.name = "subs_" + audioNameUnsafe.ReplaceAllString(track.language, "-")
+		if seen[track.name] {
+			track.name += "_" + strconv.Itoa(len(tracks))
+		}
+		seen[track.name] = true
+
+		track.label = strings.ReplaceAll(track.label, `"`, "'")
+		if track.label == "" {
+			track.label = track.language
+		}
+
+		// Players only honour one default subtitle rendition
+		track.isDefault = track.isDefault && !hasDefault
+		hasDefault = hasDefault || track.isDefault
+
+		tracks = append(tracks, track)
+	}
+
+	return tracks
+}
+
+// subtitleTrackNames returns the rendition names of subtitle tracks for
+// output metadata
+func subtitleTrackNames(tracks []subtitleTrack) string {
+	names := make([]string, len(tracks))
+	for i, track := range tracks {
+		names[i] = track.name
+	}
+	return strings.Join(names, ",")
+}
+
+// transcodeHLSSubtitles converts each subtitle track to WebVTT and writes
+// it as a segmented subtitle rendition in its own directory, laid out like
+// a video rendition. The unsegmented WebVTT goes to the job temp directory.
+func (t *Transcoder) transcodeHLSSubtitles(ctx context.Context, tracks []subtitleTrack, inputInfo *VideoInfo,
+	outputDir string, output *config.OutputConfig) ([]models.OutputFile, error) {
+
+	var files []models.OutputFile
+	for _, track := range tracks {
+		slog.Info("Converting HLS subtitles", "track", track.name, "language", track.language)
+
+		trackDir := filepath.Join(outputDir, track.name)
+		if err := os.MkdirAll(trackDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create subtitle rendition directory: %w", err)
+		}
+
+		vttPath := filepath.Join(filepath.Dir(outputDir),
+			fmt.Sprintf("subtitles-%s-%s.vtt", filepath.Base(outputDir), track.name))
+		args := buildWebVTTArgs(track, vttPath)
+
+		slog.Debug("Running FFmpeg for WebVTT conversion",
+			"track", track.name,
+			"args", strings.Join(args, " "),
+		)
+
+		if err := t.runFFmpegWithProgress(ctx, args, 0, nil); err != nil {
+			return nil, fmt.Errorf("failed to convert subtitles '%s': %w", track.name, err)
+		}
+
+		vtt, err := os.ReadFile(vttPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read converted subtitles: %w", err)
+		}
+		cues, err := parseWebVTT(vtt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subtitles '%s': %w", track.name, err)
+		}
+		if err := writeWebVTTSegments(cues, trackDir, track.name, hlsSegmentLength(output), inputInfo.Duration,
+			webVTTTimestampOffset(hlsSegmentsFor(output))); err != nil {
+			return nil, fmt.Errorf("failed to segment subtitles '%s': %w", track.name, err)
+		}
+
+		trackFiles, err := t.collectHLSProfileFiles(trackDir, track.name,
+			hlsSegments{extension: "vtt", mimeType: "text/vtt"})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, trackFiles...)
+	}
+
+	return files, nil
+}
+
+// buildWebVTTArgs builds FFmpeg arguments that convert a subtitle track to
+// a single WebVTT file
+func buildWebVTTArgs(track subtitleTrack, vttPath string) []string {
+	return []string{
+		"-i", track.inputPath,
+		"-map", fmt.Sprintf("0:s:%d", track.index),
+		"-c:s", "webvtt",
+		"-f", "webvtt",
+		"-y", vttPath,
+	}
+}
+
+// webVTTTimestampOffset returns the MPEG timestamp, in 90 kHz ticks, of
+// the start of the video segments. ffmpeg's MPEG-TS muxer starts at 1.4
+// seconds, fMP4 segments at zero.
+func webVTTTimestampOffset(segments hlsSegments) int64 {
+	if segments.fragmented {
+		return 0
+	}
+	return 126000
+}
+
+// webVTTCue is a cue of a WebVTT file, kept as written
+type webVTTCue struct {
+	start, end time.Duration
+	block      string // Identifier, timing line and payload
+}
+
+// parseWebVTT returns the cues of a WebVTT file. Header, NOTE, STYLE and
+// REGION blocks are dropped.
+func parseWebVTT(data []byte) ([]webVTTCue, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("WEBVTT")) {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+
+	var cues []webVTTCue
+	for _, block := range strings.Split(string(data), "\n\n") {
+		block = strings.Trim(block, "\n")
+		lines := strings.Split(block, "\n")
+
+		timing := -1
+		for i, line := range lines[:min(2, len(lines))] {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		fields := strings.Fields(lines[timing])
+		if len(fields) < 3 || fields[1] != "-->" {
+			return nil, fmt.Errorf("invalid cue timing: %s", lines[timing])
+		}
+		start, err := parseWebVTTTimestamp(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseWebVTTTimestamp(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, webVTTCue{start: start, end: end, block: block})
+	}
+
+	return cues, nil
+}
+
+// parseWebVTTTimestamp parses a cue timestamp, [hh:]mm:ss.ttt
+func parseWebVTTTimestamp(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", value)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp: %s", value)
+	}
+	total := seconds
+	for i, part := range parts[:len(parts)-1] {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %s", value)
+		}
+		total += float64(n) * math.Pow(60, float64(len(parts)-1-i))
+	}
+
+	return time.Duration(math.Round(total*1000)) * time.Millisecond, nil
+}
+
+// writeWebVTTSegments splits cues into WebVTT segments of segmentLength
+// seconds covering the video's duration, named like video segments, and
+// writes their playlist. A cue spanning a segment boundary is repeated in
+// each segment it overlaps, as HLS requires.
+func writeWebVTTSegments(cues []webVTTCue, dir, name string, segmentLength int, duration time.Duration,
+	timestampOffset int64) error {
+
+	length := time.Duration(segmentLength) * time.Second
+	for _, cue := range cues {
+		duration = max(duration, cue.end)
+	}
+	count := max(int((duration+length-1)/length), 1)
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:3\n")
+	playlist.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", segmentLength))
+	playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	playlist.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+
+	for i := range count {
+		start := time.Duration(i) * length
+		end := start + length
+		if i == count-1 && duration > start {
+			end = duration
+		}
+
+		var segment strings.Builder
+		segment.WriteString("WEBVTT\n")
+		segment.WriteString(fmt.Sprintf("X-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:00:00:00.000\n", timestampOffset))
+		for _, cue := range cues {
+			if cue.start < end && cue.end > start {
+				segment.WriteString("\n" + cue.block + "\n")
+			}
+		}
+
+		segmentName := fmt.Sprintf("%s_%03d.vtt", name, i)
+		if err := os.WriteFile(filepath.Join(dir, segmentName), []byte(segment.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write subtitle segment: %w", err)
+		}
+		playlist.WriteString(fmt.Sprintf("#EXTINF:%.3f,\n%s\n", (end - start).Seconds(), segmentName))
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+
+	if err := os.WriteFile(filepath.Join(dir, name+".m3u8"), []byte(playlist.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write subtitle playlist: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package transcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+)
+
+func TestSelectSubtitleTracks(t *testing.T) {
+	inputInfo := &VideoInfo{SubtitleStreams: []SubtitleStream{
+		{Codec: "subrip", Language: "eng", Title: "English SDH", Default: true},
+		{Codec: "hdmv_pgs_subtitle", Language: "fre"},
+		{Codec: "mov_text", Language: "spa", Forced: true},
+	}}
+	sidecars := []SidecarCaption{{Path: "/tmp/job/caption-0.srt", Language: "ENG", Label: "English"}}
+
+	tests := []struct {
+		name           string
+		subtitleTracks []string
+		expected       string // name:input:index, the default marked with *
+	}{
+		{"all by default", nil, "subs_eng:caption-0.srt:0 *subs_eng_1:in.mkv:0 subs_spa:in.mkv:2"},
+		{"by language", []string{"spa", "eng"}, "subs_spa:in.mkv:2 subs_eng:caption-0.srt:0"},
+		{"bitmap only", []string{"fre"}, ""},
+		{"none", []string{"none"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := &config.OutputConfig{Name: "hls", SubtitleTracks: test.subtitleTracks}
+
+			var got []string
+			for _, track := range selectSubtitleTracks(output, "in.mkv", inputInfo, sidecars) {
+				entry := fmt.Sprintf("%s:%s:%d", track.name, filepath.Base(track.inputPath), track.index)
+				if track.isDefault {
+					entry = "*" + entry
+				}
+				got = append(got, entry)
+			}
+			if strings.Join(got, " ") != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestParseWebVTT(t *testing.T) {
+	vtt := "WEBVTT\r\n\r\nNOTE converted\r\n\r\n1\r\n00:00:01.000 --> 00:00:04.500\r\nHello\r\n\r\n" +
+		"01:02.250 --> 01:05.000 align:start\r\n<i>World</i>\r\nagain\r\n"
+
+	cues, err := parseWebVTT([]byte(vtt))
+	if err != nil {
+		t.Fatalf("Failed to parse WebVTT: %v", err)
+	}
+	if len(cues) != 2 {
+		t.Fatalf("Expected 2 cues, got %v", cues)
+	}
+	if cues[0].start != time.Second || cues[0].end != 4500*time.Millisecond || cues[0].block != "1\n00:00:01.000 --> 00:00:04.500\nHello" {
+		t.Errorf("Unexpected first cue %+v", cues[0])
+	}
+	if cues[1].start != 62250*time.Millisecond || cues[1].end != 65*time.Second {
+		t.Errorf("Unexpected second cue times %v-%v", cues[1].start, cues[1].end)
+	}
+
+	if _, err := parseWebVTT([]byte("1\n00:00:01,000 --> 00:00:02,000\nSRT")); err == nil {
+		t.Error("Expected an error for a file without WEBVTT header")
+	}
+}
+
+func TestWriteWebVTTSegments(t *testing.T) {
+	dir := t.TempDir()
+	cues := []webVTTCue{
+		{start: time.Second, end: 2 * time.Second, block: "00:01.000 --> 00:02.000\nFirst"},
+		{start: 5 * time.Second, end: 7 * time.Second, block: "00:05.000 --> 00:07.000\nAcross"},
+	}
+
+	if err := writeWebVTTSegments(cues, dir, "subs_eng", 6, 10*time.Second, 126000); err != nil {
+		t.Fatalf("Failed to write segments: %v", err)
+	}
+
+	playlist, err := os.ReadFile(filepath.Join(dir, "subs_eng.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"#EXT-X-TARGETDURATION:6\n",
+		"#EXTINF:6.000,\nsubs_eng_000.vtt\n#EXTINF:4.000,\nsubs_eng_001.vtt\n#EXT-X-ENDLIST\n",
+	} {
+		if !strings.Contains(string(playlist), expected) {
+			t.Errorf("Expected %q in playlist, got:\n%s", expected, playlist)
+		}
+	}
+
+	// The cue across the boundary is in both segments
+	for i, expected := range []string{"First", "Across"} {
+		segment, err := os.ReadFile(filepath.Join(dir, []string{"subs_eng_000.vtt", "subs_eng_001.vtt"}[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(segment), "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:126000,LOCAL:00:00:00.000\n") ||
+			!strings.Contains(string(segment), expected) || !strings.Contains(string(segment), "Across") {
+			t.Errorf("Unexpected segment %d:\n%s", i, segment)
+		}
+	}
+}
+
+func TestCreateMasterPlaylist_Subtitles(t *testing.T) {
+	transcoder := &Transcoder{}
+	ladder := []config.ProfileConfig{{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2500, AudioBitrateKbps: 128}}
+	subtitles := []subtitleTrack{
+		{language: "eng", name: "subs_eng", label: "English", isDefault: true},
+		{language: "spa", name: "subs_spa", label: "spa", forced: true},
+	}
+
+	playlist, err := transcoder.createMasterPlaylist(ladder, &VideoInfo{FrameRate: 30}, hlsAudio{}, subtitles, nil)
+	if err != nil {
+		t.Fatalf("Failed to create master playlist: %v", err)
+	}
+	for _, expected := range []string{
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,FORCED=NO,URI="subs_eng/subs_eng.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="spa",LANGUAGE="spa",DEFAULT=NO,AUTOSELECT=YES,FORCED=YES,URI="subs_spa/subs_spa.m3u8"`,
+		`CODECS="avc1.4d401f",SUBTITLES="subs",NAME="720p"`,
+	} {
+		if !strings.Contains(playlist, expected) {
+			t.Errorf("Expected %s in master playlist, got:\n%s", expected, playlist)
+		}
+	}
+}
@@ -20,6 +20,7 @@ type Transcoder struct {
 	ffmpegBin  string
 	ffprobeBin string
 	tempDir    string
+	slots      slotPool // Encodes running across all jobs
 }
 
 // NewTranscoder creates a new transcoder instance
@@ -29,6 +30,16 @@ func NewTranscoder(cfg *config.Config) (*Transcoder, error) {
 		ffmpegBin:  cfg.FFmpeg.BinaryPath,
 		ffprobeBin: cfg.FFmpeg.ProbePath,
 		tempDir:    cfg.Processing.TempDir,
+		slots:      newSlotPool(encodeSlots(cfg)),
+	}
+
+	// Check codec choices before any job runs
+	for name, template := range cfg.JobTemplates {
+		for i := range template.Outputs {
+			if err := validateOutput(&template.Outputs[i]); err != nil {
+				return nil, fmt.Errorf("invalid output %s of job template %s: %w", template.Outputs[i].Name, name, err)
+			}
+		}
 	}
 
 	// Verify FFmpeg installation
@@ -44,6 +55,15 @@ func NewTranscoder(cfg *config.Config) (*Transcoder, error) {
 	return t, nil
 }
 
+// encodeSlots returns the number of encodes that may run across all jobs,
+// by default one per concurrent job
+func encodeSlots(cfg *config.Config) int {
+	if cfg.Processing.EncodeSlots > 0 {
+		return cfg.Processing.EncodeSlots
+	}
+	return cfg.Processing.MaxConcurrentJobs
+}
+
 // verifyFFmpeg checks if FFmpeg is installed and accessible
 func (t *Transcoder) verifyFFmpeg() error {
 	cmd := exec.Command(t.ffmpegBin, "-version")
@@ -77,9 +97,23 @@ type TranscodeStatistics struct {
 // ProgressCallback is called during transcoding to report progress
 type ProgressCallback func(progress float64, currentFrame, totalFrames int, speed float64)
 
-// Transcode performs video transcoding based on the job template
+// jobParallelism returns how many encodes of a job may run at once: the
+// template's setting, else the processing default
+func (t *Transcoder) jobParallelism(template *config.JobTemplate) int {
+	if template.ParallelEncodes > 0 {
+		return template.ParallelEncodes
+	}
+	if t.config != nil && t.config.Processing.MaxParallelEncodes > 0 {
+		return t.config.Processing.MaxParallelEncodes
+	}
+	return 1
+}
+
+// Transcode performs video transcoding based on the job template. Captions
+// are the job's sidecar caption files, already downloaded.
 func (t *Transcoder) Transcode(ctx context.Context, job *models.ConversionJob,
-	template *config.JobTemplate, inputPath string, progressCallback ProgressCallback) (*TranscodeResult, error) {
+	template *config.JobTemplate, inputPath string, captions []SidecarCaption,
+	progressCallback ProgressCallback) (*TranscodeResult, error) {
 
 	startTime := time.Now()
 	slog.Info("Starting transcoding",
@@ -101,12 +135,17 @@ func (t *Transcoder) Transcode(ctx context.Context, job *models.ConversionJob,
 		return nil, fmt.Errorf("failed to get input video info: %w", err)
 	}
 
-	var outputs []models.ConversionOutput
-	var totalProcessingTime time.Duration
-	outputSizes := make(map[string]int64)
+	// Limit the job's concurrent encodes; outputs and their renditions are
+	// only encoded in parallel if the job may run more than one
+	parallelism := t.jobParallelism(template)
+	ctx = withJobSlots(ctx, newSlotPool(parallelism))
+
+	results := make([]*models.ConversionOutput, len(template.Outputs))
+	progress := newProgressStages(progressCallback, len(template.Outputs))
 
 	// Process each output configuration
-	for i, output := range template.Outputs {
+	err = forEach(ctx, len(template.Outputs), parallelism > 1, func(ctx context.Context, i int) error {
+		output := template.Outputs[i]
 		slog.Info("Processing output",
 			"jobId", job.JobID,
 			"outputIndex", i,
@@ -114,12 +153,24 @@ func (t *Transcoder) Transcode(ctx context.Context, job *models.ConversionJob,
 			"package", output.Package,
 		)
 
-		outputResult, err := t.processOutput(ctx, inputPath, &output, jobTempDir,
-			inputInfo, template.FFmpeg, progressCallback)
+		outputResult, err := t.processOutput(ctx, inputPath, captions, &output, jobTempDir,
+			inputInfo, template.FFmpeg, parallelism > 1, progress.stage(i))
 		if err != nil {
-			return nil, fmt.Errorf("failed to process output '%s': %w", output.Name, err)
+			return fmt.Errorf("failed to process output '%s': %w", output.Name, err)
 		}
+		progress.complete(i)
+		results[i] = outputResult
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []models.ConversionOutput
+	var totalProcessingTime time.Duration
+	outputSizes := make(map[string]int64)
 
+	for _, outputResult := range results {
 		outputs = append(outputs, *outputResult)
 
 		// Extract processing time from metadata
@@ -169,9 +220,9 @@ func (t *Transcoder) Transcode(ctx context.Context, job *models.ConversionJob,
 }
 
 // processOutput handles a single output configuration
-func (t *Transcoder) processOutput(ctx context.Context, inputPath string,
+func (t *Transcoder) processOutput(ctx context.Context, inputPath string, captions []SidecarCaption,
 	output *config.OutputConfig, jobTempDir string, inputInfo *VideoInfo,
-	ffmpegConfig config.JobFFmpegConfig, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
+	ffmpegConfig config.JobFFmpegConfig, parallel bool, progressCallback ProgressCallback) (*models.ConversionOutput, error) {
 
 	outputDir := filepath.Join(jobTempDir, output.Name)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -180,9 +231,11 @@ func (t *Transcoder) processOutput(ctx context.Context, inputPath string,
 
 	switch strings.ToLower(output.Package) {
 	case "hls":
-		return t.transcodeHLS(ctx, inputPath, output, outputDir, inputInfo, ffmpegConfig, progressCallback)
+		return t.transcodeHLS(ctx, inputPath, captions, output, outputDir, inputInfo, ffmpegConfig, parallel, progressCallback)
+	case "dash":
+		return t.transcodeDASH(ctx, inputPath, output, outputDir, inputInfo, ffmpegConfig, progressCallback)
 	case "progressive", "mp4":
-		return t.transcodeProgressive(ctx, inputPath, output, outputDir, inputInfo, ffmpegConfig, progressCallback)
+		return t.transcodeProgressive(ctx, inputPath, output, outputDir, inputInfo, ffmpegConfig, parallel, progressCallback)
 	default:
 		return nil, fmt.Errorf("unsupported package type: %s", output.Package)
 	}
@@ -2,9 +2,11 @@ package transcoder
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -22,8 +24,38 @@ type VideoInfo struct {
 	Size        int64         `json:"size"`
 	Format      string        `json:"format"`
 	VideoCodec  string        `json:"videoCodec"`
-	AudioCodec  string        `json:"audioCodec"`
+	AudioCodec  string        `json:"audioCodec"` // Codec of the first audio stream
 	TotalFrames int           `json:"totalFrames"`
+	Rotation    int           `json:"rotation"` // Clockwise display rotation in degrees: 0, 90, 180 or 270
+
+	AudioStreams    []AudioStream    `json:"audioStreams"`    // In source order, so the Nth is input stream 0:a:N
+	SubtitleStreams []SubtitleStream `json:"subtitleStreams"` // In source order, so the Nth is input stream 0:s:N
+}
+
+// AudioStream describes an audio stream of a video file
+type AudioStream struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language"` // ISO 639-2 language tag, empty if untagged
+	Title    string `json:"title"`
+	Channels int    `json:"channels"`
+	Default  bool   `json:"default"` // Marked as the default track
+}
+
+// SubtitleStream describes a subtitle stream of a video file
+type SubtitleStream struct {
+	Codec    string `json:"codec"`
+	Language string `json:"language"` // ISO 639-2 language tag, empty if untagged
+	Title    string `json:"title"`
+	Default  bool   `json:"default"` // Marked as the default track
+	Forced   bool   `json:"forced"`  // Only translates foreign dialogue or signs
+}
+
+// displaySize returns the video dimensions as displayed, after rotation
+func (v *VideoInfo) displaySize() (int, int) {
+	if v.Rotation == 90 || v.Rotation == 270 {
+		return v.Height, v.Width
+	}
+	return v.Width, v.Height
 }
 
 // FFprobeOutput represents the structure of ffprobe JSON output
@@ -37,9 +69,21 @@ type FFprobeOutput struct {
 		RFrameRate string `json:"r_frame_rate"`
 		Duration   string `json:"duration"`
 		BitRate    string `json:"bit_rate"`
+		Channels   int    `json:"channels"`
 		Tags       struct {
 			Duration string `json:"DURATION"`
+			Rotate   string `json:"rotate"`
+			Language string `json:"language"`
+			Title    string `json:"title"`
 		} `json:"tags"`
+		Disposition struct {
+			Default int `json:"default"`
+			Forced  int `json:"forced"`
+		} `json:"disposition"`
+		SideDataList []struct {
+			SideDataType string  `json:"side_data_type"`
+			Rotation     float64 `json:"rotation"`
+		} `json:"side_data_list"`
 	} `json:"streams"`
 	Format struct {
 		Filename   string `json:"filename"`
@@ -57,16 +101,23 @@ type FFprobeOutput struct {
 func (t *Transcoder) getVideoInfo(ctx context.Context, inputPath string) (*VideoInfo, error) {
 	// Use ffprobe to get detailed video information
 	cmd := exec.CommandContext(ctx, t.ffprobeBin,
-		"-v", "quiet",
+		"-v", "error",
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
 		inputPath,
 	)
 
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to run ffprobe: %w", err)
+		var tail stderrTail
+		for _, line := range strings.Split(stderr.String(), "\n") {
+			tail.add(line)
+		}
+		return nil, fmt.Errorf("failed to run ffprobe: %w", newFFmpegError("ffprobe", err, tail.String()))
 	}
 
 	var probe FFprobeOutput
@@ -104,6 +155,20 @@ func (t *Transcoder) getVideoInfo(ctx context.Context, inputPath string) (*Video
 			info.Height = stream.Height
 			info.VideoCodec = stream.CodecName
 
+			// Phones record portrait video as landscape frames with a
+			// display matrix (or, from older muxers, a rotate tag)
+			rotation := 0.0
+			if rotate, err := strconv.ParseFloat(stream.Tags.Rotate, 64); err == nil {
+				rotation = rotate
+			}
+			for _, sideData := range stream.SideDataList {
+				if sideData.SideDataType == "Display Matrix" {
+					// The display matrix angle is counterclockwise
+					rotation = -sideData.Rotation
+				}
+			}
+			info.Rotation = normalizeRotation(rotation)
+
 			// Parse frame rate
 			if stream.RFrameRate != "" {
 				if frameRate := parseFrameRate(stream.RFrameRate); frameRate > 0 {
@@ -121,15 +186,47 @@ func (t *Transcoder) getVideoInfo(ctx context.Context, inputPath string) (*Video
 
 	// Parse audio stream information
 	for _, stream := range probe.Streams {
-		if stream.CodecType == "audio" {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		if info.AudioCodec == "" {
 			info.AudioCodec = stream.CodecName
-			break
 		}
+		info.AudioStreams = append(info.AudioStreams, AudioStream{
+			Codec:    stream.CodecName,
+			Language: stream.Tags.Language,
+			Title:    stream.Tags.Title,
+			Channels: stream.Channels,
+			Default:  stream.Disposition.Default == 1,
+		})
+	}
+
+	// Parse subtitle stream information
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "subtitle" {
+			continue
+		}
+		info.SubtitleStreams = append(info.SubtitleStreams, SubtitleStream{
+			Codec:    stream.CodecName,
+			Language: stream.Tags.Language,
+			Title:    stream.Tags.Title,
+			Default:  stream.Disposition.Default == 1,
+			Forced:   stream.Disposition.Forced == 1,
+		})
 	}
 
 	return info, nil
 }
 
+// normalizeRotation maps a rotation in degrees to 0, 90, 180 or 270
+func normalizeRotation(degrees float64) int {
+	rotation := int(math.Round(degrees/90)) * 90 % 360
+	if rotation < 0 {
+		rotation += 360
+	}
+	return rotation
+}
+
 // parseFrameRate parses frame rate string like "30/1" or "29.97"
 func parseFrameRate(frameRateStr string) float64 {
 	if strings.Contains(frameRateStr, "/") {
@@ -195,6 +292,13 @@ func parseProgress(line string) *ProgressInfo {
 func (t *Transcoder) runFFmpegWithProgress(ctx context.Context, args []string,
 	totalFrames int, progressCallback ProgressCallback) error {
 
+	// Wait for an encode slot of the job and of the node
+	release, err := t.acquireEncodeSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	cmd := exec.CommandContext(ctx, t.ffmpegBin, args...)
 
 	// Get stderr pipe to read progress
@@ -207,22 +311,31 @@ func (t *Transcoder) runFFmpegWithProgress(ctx context.Context, args []string,
 		return fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
 
-	// Monitor progress
+	// Monitor progress, keeping the tail of other stderr output for error reports
+	var tail stderrTail
 	scanner := bufio.NewScanner(stderr)
-	go func() {
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			// Parse progress information
-			if progress := parseProgress(line); progress != nil && progressCallback != nil {
-				var progressPercent float64
-				if totalFrames > 0 {
-					progressPercent = float64(progress.Frame) / float64(totalFrames)
-				}
-				progressCallback(progressPercent, progress.Frame, totalFrames, progress.Speed)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Parse progress information
+		progress := parseProgress(line)
+		if progress == nil {
+			tail.add(line)
+			continue
+		}
+		if progressCallback != nil {
+			var progressPercent float64
+			if totalFrames > 0 {
+				progressPercent = float64(progress.Frame) / float64(totalFrames)
 			}
+			progressCallback(progressPercent, progress.Frame, totalFrames, progress.Speed)
 		}
-	}()
+	}
 
-	return cmd.Wait()
+	// Stderr must be fully read before Wait closes the pipe
+	if err := cmd.Wait(); err != nil {
+		return newFFmpegError("ffmpeg", err, tail.String())
+	}
+	return nil
 }
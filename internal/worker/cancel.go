@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// errJobCancelled is the cancellation cause of a running job's context when
+// CancelJob stops it, distinguishing it from shutdown and timeouts
+var errJobCancelled = errors.New("job cancelled")
+
+// runningJob is the cancel handle of a job being processed, along with the
+// storage paths it has written so a cancelled job can remove them
+type runningJob struct {
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	uploaded []string
+}
+
+// recordUpload notes a file being written to output storage
+func (r *runningJob) recordUpload(destPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.uploaded = append(r.uploaded, destPath)
+}
+
+// uploads returns the storage paths written so far
+func (r *runningJob) uploads() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.uploaded...)
+}
+
+// trackJob registers a job as running and returns its handle
+func (w *Worker) trackJob(jobID string, cancel context.CancelCauseFunc) *runningJob {
+	run := &runningJob{cancel: cancel}
+
+	w.runningMu.Lock()
+	defer w.runningMu.Unlock()
+	w.running[jobID] = run
+	return run
+}
+
+// untrackJob removes a job's handle once it can no longer be cancelled
+func (w *Worker) untrackJob(jobID string) {
+	w.runningMu.Lock()
+	defer w.runningMu.Unlock()
+	delete(w.running, jobID)
+}
+
+// runningJob returns the handle of a job being processed, or nil
+func (w *Worker) runningJob(jobID string) *runningJob {
+	w.runningMu.Lock()
+	defer w.runningMu.Unlock()
+	return w.running[jobID]
+}
+
+// finishCancelledJob cleans up after a running job was cancelled: the job
+// temp directory and any outputs already uploaded are removed before the
+// cancelled state is recorded
+func (w *Worker) finishCancelledJob(job *models.ConversionJob, run *runningJob) {
+	jobTempDir := filepath.Join(w.config.Processing.TempDir, job.JobID)
+	if err := os.RemoveAll(jobTempDir); err != nil {
+		slog.Warn("Failed to clean up job temp directory", "jobId", job.JobID, "path", jobTempDir, "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), time.Minute)
+	defer cancel()
+
+	uploaded := run.uploads()
+	for _, destPath := range uploaded {
+		if err := w.outputStorage.DeleteFile(ctx, destPath); err != nil {
+			slog.Warn("Failed to delete partial output of cancelled job",
+				"jobId", job.JobID,
+				"path", destPath,
+				"error", err,
+			)
+		}
+	}
+
+	w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+		job.Status.State = models.JobStateCancelled
+		job.Status.Message = "Job cancelled during processing"
+		job.Status.Error = ""
+		job.Status.CompletedAt = time.Now()
+	})
+
+	slog.Info("Job cancelled",
+		"jobId", job.JobID,
+		"deletedOutputs", len(uploaded),
+	)
+}
@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/storage"
+	"github.com/matt-primrose/video-converter-service/internal/transcoder"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestCancelJob_RunningJobIsCleanedUp(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "temp")
+	outputDir := filepath.Join(dir, "outputs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &Worker{
+		config:        &config.Config{Processing: config.ProcessingConfig{TempDir: tempDir}},
+		outputStorage: storage.NewLocalStorage(outputDir, storage.StorageConfig{TempDir: tempDir}),
+		registry:      newJobRegistry(time.Hour, 10),
+		store:         NewMemoryJobStore(),
+		dedup:         NewMemoryDedupStore(),
+		updates:       newUpdateHub(),
+		running:       make(map[string]*runningJob),
+		ctx:           ctx,
+	}
+
+	job := &models.ConversionJob{JobID: "job-1", Status: models.JobStatus{State: models.JobStateProcessing}}
+	if err := w.registry.Add(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	// A running job with a temp file and one output already uploaded
+	jobTempDir := filepath.Join(tempDir, job.JobID)
+	if err := os.MkdirAll(jobTempDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	segment := filepath.Join(jobTempDir, "segment.ts")
+	if err := os.WriteFile(segment, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.outputStorage.UploadFile(ctx, segment, "videos/job-1/segment.ts"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	run := w.trackJob(job.JobID, cancelRun)
+	run.recordUpload("videos/job-1/segment.ts")
+
+	cancelling, err := w.CancelJob(job.JobID)
+	if err != nil {
+		t.Fatalf("Failed to cancel running job: %v", err)
+	}
+	if cancelling.Status.State != models.JobStateProcessing {
+		t.Errorf("Expected job to stay processing until cleaned up, got %s", cancelling.Status.State)
+	}
+	if !errors.Is(context.Cause(runCtx), errJobCancelled) {
+		t.Fatalf("Expected job context to be cancelled, cause %v", context.Cause(runCtx))
+	}
+
+	w.untrackJob(job.JobID)
+	w.finishCancelledJob(job, run)
+
+	if _, err := os.Stat(jobTempDir); !os.IsNotExist(err) {
+		t.Errorf("Expected job temp directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "videos/job-1/segment.ts")); !os.IsNotExist(err) {
+		t.Errorf("Expected partial output to be deleted, got %v", err)
+	}
+	if state, _ := w.registry.State(job.JobID); state != models.JobStateCancelled {
+		t.Errorf("Expected cancelled state, got %s", state)
+	}
+
+	if _, err := w.CancelJob(job.JobID); !errors.Is(err, ErrJobNotCancellable) {
+		t.Errorf("Expected ErrJobNotCancellable for a cancelled job, got %v", err)
+	}
+}
+
+// interruptedStorage writes each upload, then fails it as a cancelled
+// multipart upload that left an object behind would
+type interruptedStorage struct {
+	*storage.LocalStorage
+}
+
+func (s interruptedStorage) UploadFile(ctx context.Context, sourcePath, destinationPath string) error {
+	if err := s.LocalStorage.UploadFile(ctx, sourcePath, destinationPath); err != nil {
+		return err
+	}
+	return context.Canceled
+}
+
+func TestUploadOutputFiles_RecordsInterruptedUpload(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "temp")
+	w := &Worker{
+		config:        &config.Config{Processing: config.ProcessingConfig{TempDir: tempDir}},
+		outputStorage: interruptedStorage{storage.NewLocalStorage(filepath.Join(dir, "outputs"), storage.StorageConfig{TempDir: tempDir})},
+	}
+
+	segment := filepath.Join(tempDir, "job-1", "hls", "segment.ts")
+	if err := os.MkdirAll(filepath.Dir(segment), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(segment, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &models.ConversionJob{JobID: "job-1"}
+	result := &transcoder.TranscodeResult{Outputs: []models.ConversionOutput{
+		{Name: "hls", Files: []models.OutputFile{{Path: segment}}},
+	}}
+	run := &runningJob{}
+	if err := w.uploadOutputFiles(context.Background(), job, &config.JobTemplate{}, result, run); err == nil {
+		t.Fatal("Expected the upload to fail")
+	}
+	if uploads := run.uploads(); len(uploads) != 1 || uploads[0] != "job-1/hls/segment.ts" {
+		t.Errorf("Expected the interrupted upload to be recorded for cleanup, got %v", uploads)
+	}
+}
@@ -0,0 +1,401 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/storage"
+	"github.com/matt-primrose/video-converter-service/internal/transcoder"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// ErrDeadLetterNotFound is returned when no dead letter exists for a job ID
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterStore keeps permanently failed jobs for triage and replay
+type DeadLetterStore interface {
+	// Put creates or replaces the dead letter for a job
+	Put(ctx context.Context, letter *models.DeadLetter) error
+
+	// Get returns the dead letter for a job, or ErrDeadLetterNotFound
+	Get(ctx context.Context, jobID string) (*models.DeadLetter, error)
+
+	// List returns every dead letter
+	List(ctx context.Context) ([]*models.DeadLetter, error)
+
+	// Delete removes the dead letter for a job
+	Delete(ctx context.Context, jobID string) error
+}
+
+// NewDeadLetterStore creates a dead-letter store based on configuration.
+// The storage type writes dead letters through the output storage backend.
+func NewDeadLetterStore(cfg *config.Config, outputStorage storage.Storage) (DeadLetterStore, error) {
+	deadLetterConfig := cfg.Processing.DeadLetter
+
+	switch deadLetterConfig.Type {
+	case "", "disk":
+		return NewDiskDeadLetterStore(deadLetterConfig.Path)
+	case "storage":
+		return NewStorageDeadLetterStore(outputStorage, deadLetterConfig.Prefix, cfg.Processing.TempDir), nil
+	default:
+		return nil, fmt.Errorf("unsupported dead letter store type: %s", deadLetterConfig.Type)
+	}
+}
+
+// newDeadLetter builds the dead letter for a job that failed permanently
+func newDeadLetter(job *models.ConversionJob, err error) *models.DeadLetter {
+	letter := &models.DeadLetter{
+		Job:      *cloneJob(job),
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+	}
+
+	var ffmpegErr *transcoder.FFmpegError
+	if errors.As(err, &ffmpegErr) {
+		letter.Stderr = ffmpegErr.Stderr
+	}
+
+	return letter
+}
+
+// deadLetterFileName returns the file name used for a job's dead letter
+func deadLetterFileName(jobID string) (string, error) {
+	if jobID == "" || strings.ContainsAny(jobID, `/\`) || jobID == "." || jobID == ".." {
+		return "", fmt.Errorf("invalid job ID: %q", jobID)
+	}
+	return jobID + ".json", nil
+}
+
+// sortDeadLetters orders dead letters newest first
+func sortDeadLetters(letters []*models.DeadLetter) {
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.After(letters[j].FailedAt)
+	})
+}
+
+// DiskDeadLetterStore implements DeadLetterStore as one JSON file per job
+// in a local directory
+type DiskDeadLetterStore struct {
+	dir string
+}
+
+// NewDiskDeadLetterStore creates a dead-letter store in the given directory
+func NewDiskDeadLetterStore(dir string) (*DiskDeadLetterStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("dead letter directory is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+	return &DiskDeadLetterStore{dir: dir}, nil
+}
+
+// Put writes the dead letter, replacing the file atomically
+func (ds *DiskDeadLetterStore) Put(ctx context.Context, letter *models.DeadLetter) error {
+	name, err := deadLetterFileName(letter.Job.JobID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	tempPath := filepath.Join(ds.dir, "."+name+".tmp")
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := os.Rename(tempPath, filepath.Join(ds.dir, name)); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return nil
+}
+
+// Get reads the dead letter for a job
+func (ds *DiskDeadLetterStore) Get(ctx context.Context, jobID string) (*models.DeadLetter, error) {
+	name, err := deadLetterFileName(jobID)
+	if err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	return readDeadLetterFile(filepath.Join(ds.dir, name))
+}
+
+// List reads every dead letter in the directory
+func (ds *DiskDeadLetterStore) List(ctx context.Context) ([]*models.DeadLetter, error) {
+	entries, err := os.ReadDir(ds.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter directory: %w", err)
+	}
+
+	var letters []*models.DeadLetter
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		letter, err := readDeadLetterFile(filepath.Join(ds.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// Delete removes the dead letter file for a job
+func (ds *DiskDeadLetterStore) Delete(ctx context.Context, jobID string) error {
+	name, err := deadLetterFileName(jobID)
+	if err != nil {
+		return ErrDeadLetterNotFound
+	}
+	if err := os.Remove(filepath.Join(ds.dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// readDeadLetterFile decodes a dead letter from a local JSON file
+func readDeadLetterFile(filePath string) (*models.DeadLetter, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+
+	var letter models.DeadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter %s: %w", filepath.Base(filePath), err)
+	}
+	return &letter, nil
+}
+
+// StorageDeadLetterStore implements DeadLetterStore on top of a storage
+// backend, one JSON object per job under a key prefix
+type StorageDeadLetterStore struct {
+	storage storage.Storage
+	prefix  string
+	tempDir string
+}
+
+// NewStorageDeadLetterStore creates a dead-letter store that writes through
+// the given storage backend
+func NewStorageDeadLetterStore(backend storage.Storage, prefix, tempDir string) *StorageDeadLetterStore {
+	return &StorageDeadLetterStore{
+		storage: backend,
+		prefix:  strings.Trim(prefix, "/"),
+		tempDir: tempDir,
+	}
+}
+
+// key returns the storage path of a job's dead letter
+func (ss *StorageDeadLetterStore) key(jobID string) (string, error) {
+	name, err := deadLetterFileName(jobID)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(ss.prefix, name), nil
+}
+
+// Put uploads the dead letter
+func (ss *StorageDeadLetterStore) Put(ctx context.Context, letter *models.DeadLetter) error {
+	key, err := ss.key(letter.Job.JobID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(ss.tempDir, "dead-letter-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := ss.storage.UploadFile(ctx, tempFile.Name(), key); err != nil {
+		return fmt.Errorf("failed to upload dead letter: %w", err)
+	}
+	return nil
+}
+
+// Get downloads the dead letter for a job
+func (ss *StorageDeadLetterStore) Get(ctx context.Context, jobID string) (*models.DeadLetter, error) {
+	key, err := ss.key(jobID)
+	if err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	// Storage backends only download by URL, so look the key up first to
+	// tell a missing dead letter from a failed download
+	keys, err := ss.storage.ListFiles(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	found := false
+	for _, k := range keys {
+		if strings.TrimPrefix(k, "/") == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return ss.download(ctx, key)
+}
+
+// List downloads every dead letter under the prefix
+func (ss *StorageDeadLetterStore) List(ctx context.Context) ([]*models.DeadLetter, error) {
+	listPrefix := ss.prefix
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+
+	keys, err := ss.storage.ListFiles(ctx, listPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	var letters []*models.DeadLetter
+	for _, key := range keys {
+		if path.Ext(key) != ".json" {
+			continue
+		}
+		letter, err := ss.download(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// Delete removes the dead letter for a job
+func (ss *StorageDeadLetterStore) Delete(ctx context.Context, jobID string) error {
+	if _, err := ss.Get(ctx, jobID); err != nil {
+		return err
+	}
+
+	key, _ := ss.key(jobID)
+	if err := ss.storage.DeleteFile(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+// download fetches and decodes the dead letter stored at key
+func (ss *StorageDeadLetterStore) download(ctx context.Context, key string) (*models.DeadLetter, error) {
+	fileURL, err := ss.storage.GetFileURL(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dead letter URL: %w", err)
+	}
+
+	// Backends download into tempDir/<id>/; a unique ID keeps concurrent reads apart
+	downloadID := "dead-letter-" + GenerateJobID()
+	defer os.RemoveAll(filepath.Join(ss.tempDir, downloadID))
+
+	localPath, err := ss.storage.DownloadFile(ctx, fileURL, downloadID, "dead-letter")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download dead letter %s: %w", key, err)
+	}
+
+	return readDeadLetterFile(localPath)
+}
+
+// deadLetter records a permanently failed job in the dead-letter store
+func (w *Worker) deadLetter(job *models.ConversionJob, err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), time.Minute)
+	defer cancel()
+
+	if putErr := w.deadLetters.Put(ctx, newDeadLetter(job, err)); putErr != nil {
+		slog.Error("Failed to write dead letter", "jobId", job.JobID, "error", putErr)
+		return
+	}
+	slog.Info("Job moved to dead-letter store", "jobId", job.JobID)
+}
+
+// ListDeadLetters returns every dead letter, newest first
+func (w *Worker) ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	return w.deadLetters.List(ctx)
+}
+
+// GetDeadLetter returns the dead letter for a failed job
+func (w *Worker) GetDeadLetter(ctx context.Context, jobID string) (*models.DeadLetter, error) {
+	return w.deadLetters.Get(ctx, jobID)
+}
+
+// DeleteDeadLetter discards the dead letter for a failed job
+func (w *Worker) DeleteDeadLetter(ctx context.Context, jobID string) error {
+	return w.deadLetters.Delete(ctx, jobID)
+}
+
+// ReplayDeadLetter submits a failed job again as a new job, optionally with
+// a different template, and removes its dead letter once it is queued
+func (w *Worker) ReplayDeadLetter(ctx context.Context, jobID, template string) (*models.ConversionJob, error) {
+	letter, err := w.deadLetters.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.ConversionJob{
+		CorrelationID: letter.Job.CorrelationID,
+		VideoID:       letter.Job.VideoID,
+		Template:      letter.Job.Template,
+		Source:        letter.Job.Source,
+		Captions:      letter.Job.Captions,
+		Metadata:      letter.Job.Metadata,
+	}
+	if template != "" {
+		job.Template = template
+	}
+
+	if err := w.ValidateJob(job); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if err := w.SubmitJob(job); err != nil {
+		return nil, err
+	}
+
+	if err := w.deadLetters.Delete(ctx, jobID); err != nil {
+		slog.Warn("Failed to remove replayed dead letter", "jobId", jobID, "error", err)
+	}
+
+	slog.Info("Replayed dead letter",
+		"jobId", jobID,
+		"newJobId", job.JobID,
+		"template", job.Template,
+	)
+
+	return w.GetJob(job.JobID)
+}
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/storage"
+	"github.com/matt-primrose/video-converter-service/internal/transcoder"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestDeadLetterStores(t *testing.T) {
+	dir := t.TempDir()
+	diskStore, err := NewDiskDeadLetterStore(filepath.Join(dir, "dead-letters"))
+	if err != nil {
+		t.Fatalf("Failed to create disk store: %v", err)
+	}
+	backend := storage.NewLocalStorage(filepath.Join(dir, "outputs"), storage.StorageConfig{TempDir: dir})
+
+	stores := map[string]DeadLetterStore{
+		"disk":    diskStore,
+		"storage": NewStorageDeadLetterStore(backend, "dead-letters/", dir),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if _, err := store.Get(ctx, "job-1"); !errors.Is(err, ErrDeadLetterNotFound) {
+				t.Fatalf("Expected ErrDeadLetterNotFound, got %v", err)
+			}
+
+			older := newDeadLetter(&models.ConversionJob{JobID: "job-1", Attempts: 3}, errors.New("upload failed"))
+			older.FailedAt = time.Now().Add(-time.Hour)
+			ffmpegErr := &transcoder.FFmpegError{Tool: "ffmpeg", ExitCode: 1, Stderr: "Invalid data found"}
+			newer := newDeadLetter(&models.ConversionJob{JobID: "job-2"}, ffmpegErr)
+
+			for _, letter := range []*models.DeadLetter{older, newer} {
+				if err := store.Put(ctx, letter); err != nil {
+					t.Fatalf("Failed to put dead letter: %v", err)
+				}
+			}
+
+			got, err := store.Get(ctx, "job-2")
+			if err != nil {
+				t.Fatalf("Failed to get dead letter: %v", err)
+			}
+			if got.Stderr != "Invalid data found" {
+				t.Errorf("Expected ffmpeg stderr to be kept, got %q", got.Stderr)
+			}
+
+			letters, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("Failed to list dead letters: %v", err)
+			}
+			if len(letters) != 2 || letters[0].Job.JobID != "job-2" || letters[1].Job.Attempts != 3 {
+				t.Fatalf("Expected dead letters newest first, got %+v", letters)
+			}
+
+			if err := store.Delete(ctx, "job-1"); err != nil {
+				t.Fatalf("Failed to delete dead letter: %v", err)
+			}
+			if err := store.Delete(ctx, "job-1"); !errors.Is(err, ErrDeadLetterNotFound) {
+				t.Errorf("Expected ErrDeadLetterNotFound on second delete, got %v", err)
+			}
+			if err := store.Put(ctx, &models.DeadLetter{Job: models.ConversionJob{JobID: "../escape"}}); err == nil {
+				t.Error("Expected job ID with a path separator to be rejected")
+			}
+		})
+	}
+}
+
+func TestReplayDeadLetter_KeepsJobInputs(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: filepath.Join(dir, "dead-letters")},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+
+	ctx := context.Background()
+	failed := &models.ConversionJob{
+		JobID:    "job-1",
+		VideoID:  "video-1",
+		Template: "default",
+		Source:   models.SourceConfig{URI: "https://example.com/video.mp4", Type: "http"},
+		Captions: []models.CaptionSource{
+			{URI: "https://example.com/video.en.srt", Type: "http", Language: "eng", Default: true},
+			{URI: "https://example.com/video.es.vtt", Type: "http", Language: "spa", Label: "Español"},
+		},
+		Metadata: map[string]string{"tenant": "acme"},
+	}
+	if err := w.deadLetters.Put(ctx, newDeadLetter(failed, errors.New("upload failed"))); err != nil {
+		t.Fatalf("Failed to put dead letter: %v", err)
+	}
+
+	replayed, err := w.ReplayDeadLetter(ctx, "job-1", "")
+	if err != nil {
+		t.Fatalf("Failed to replay dead letter: %v", err)
+	}
+	if replayed.JobID == failed.JobID || replayed.Status.State != models.JobStatePending {
+		t.Errorf("Expected a new pending job, got %s in state %s", replayed.JobID, replayed.Status.State)
+	}
+	if !reflect.DeepEqual(replayed.Captions, failed.Captions) {
+		t.Errorf("Expected captions %+v to survive the replay, got %+v", failed.Captions, replayed.Captions)
+	}
+	if replayed.Source != failed.Source || replayed.Metadata["tenant"] != "acme" {
+		t.Errorf("Expected source and metadata to survive the replay, got %+v", replayed)
+	}
+	if _, err := w.deadLetters.Get(ctx, "job-1"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected the replayed dead letter to be removed, got %v", err)
+	}
+}
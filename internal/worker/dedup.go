@@ -0,0 +1,501 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// dedupPruneInterval is how often expired deduplication keys are removed
+const dedupPruneInterval = 10 * time.Minute
+
+// EventGridDedupKey identifies an Event Grid event across redeliveries
+func EventGridDedupKey(eventID string) string {
+	return "eventgrid:" + eventID
+}
+
+// SourceDedupKey identifies a version of a source file by its URI and ETag
+// or checksum
+func SourceDedupKey(uri, etag string) string {
+	return "source:" + uri + "#" + etag
+}
+
+// IdempotencyDedupKey identifies a client-supplied idempotency key
+func IdempotencyDedupKey(key string) string {
+	return "idempotency:" + key
+}
+
+// DedupStore maps deduplication keys to the job they created until they
+// expire, so redelivered events and retried requests resolve to that job.
+// A snapshot of the job is kept with its keys so duplicates still resolve to
+// it, and its outcome, after it has left the job registry.
+type DedupStore interface {
+	// Claim records keys for jobID. If any key is already held by an
+	// unexpired entry, nothing is recorded and that entry's job ID is
+	// returned with claimed false.
+	Claim(keys []string, jobID string, expiresAt time.Time) (existingJobID string, claimed bool, err error)
+
+	// Release removes keys that are held by jobID, and its snapshot
+	Release(keys []string, jobID string) error
+
+	// Record replaces the snapshot of a job that holds keys. Jobs that
+	// hold none are ignored.
+	Record(job *models.ConversionJob) error
+
+	// Job returns the last recorded snapshot of a job holding keys, or
+	// ErrJobNotFound
+	Job(jobID string) (*models.ConversionJob, error)
+
+	// Prune removes expired keys and snapshots and returns how many keys
+	// were removed
+	Prune(now time.Time) (int, error)
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// NewDedupStore creates a deduplication store based on configuration
+func NewDedupStore(cfg *config.Config) (DedupStore, error) {
+	dedupConfig := cfg.Processing.Dedup
+
+	switch dedupConfig.Type {
+	case "", "bolt":
+		return NewBoltDedupStore(dedupConfig.Path)
+	case "memory":
+		return NewMemoryDedupStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported dedup store type: %s", dedupConfig.Type)
+	}
+}
+
+// dedupEntry is the stored value of a deduplication key
+type dedupEntry struct {
+	JobID     string    `json:"jobId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// dedupJob is the stored snapshot of a job holding dedup keys. It expires
+// with the keys claimed for the job.
+type dedupJob struct {
+	Job       *models.ConversionJob `json:"job"`
+	ExpiresAt time.Time             `json:"expiresAt"`
+}
+
+// Dedup BoltDB buckets: entries keyed by dedup key, and job snapshots keyed
+// by job ID
+var (
+	dedupBucket     = []byte("dedup")
+	dedupJobsBucket = []byte("dedup-jobs")
+)
+
+// BoltDedupStore implements DedupStore using an embedded BoltDB file
+type BoltDedupStore struct {
+	db *bolt.DB
+}
+
+// NewBoltDedupStore opens (or creates) a BoltDB dedup store at the given path
+func NewBoltDedupStore(path string) (*BoltDedupStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("dedup store path is required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dedup store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dedup store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{dedupBucket, dedupJobsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize dedup store: %w", err)
+	}
+
+	return &BoltDedupStore{db: db}, nil
+}
+
+// Claim checks and records the keys in a single transaction
+func (bs *BoltDedupStore) Claim(keys []string, jobID string, expiresAt time.Time) (string, bool, error) {
+	data, err := json.Marshal(dedupEntry{JobID: jobID, ExpiresAt: expiresAt})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to encode dedup entry: %w", err)
+	}
+	jobData, err := json.Marshal(dedupJob{Job: &models.ConversionJob{JobID: jobID}, ExpiresAt: expiresAt})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to encode dedup job: %w", err)
+	}
+
+	now := time.Now()
+	existing := ""
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dedupBucket)
+		for _, key := range keys {
+			value := bucket.Get([]byte(key))
+			if value == nil {
+				continue
+			}
+			var entry dedupEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return fmt.Errorf("failed to decode dedup entry %s: %w", key, err)
+			}
+			if entry.ExpiresAt.After(now) {
+				existing = entry.JobID
+				return nil
+			}
+		}
+
+		for _, key := range keys {
+			if err := bucket.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(dedupJobsBucket).Put([]byte(jobID), jobData)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return existing, existing == "", nil
+}
+
+// Release deletes the keys held by jobID
+func (bs *BoltDedupStore) Release(keys []string, jobID string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dedupBucket)
+		for _, key := range keys {
+			var entry dedupEntry
+			if value := bucket.Get([]byte(key)); value == nil || json.Unmarshal(value, &entry) != nil || entry.JobID != jobID {
+				continue
+			}
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(dedupJobsBucket).Delete([]byte(jobID))
+	})
+}
+
+// Record replaces the stored snapshot of the job, keeping its expiry
+func (bs *BoltDedupStore) Record(job *models.ConversionJob) error {
+	// Most jobs hold no keys; skip the write transaction for them
+	held := false
+	bs.db.View(func(tx *bolt.Tx) error {
+		held = tx.Bucket(dedupJobsBucket).Get([]byte(job.JobID)) != nil
+		return nil
+	})
+	if !held {
+		return nil
+	}
+
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dedupJobsBucket)
+		value := bucket.Get([]byte(job.JobID))
+		if value == nil {
+			return nil
+		}
+		var record dedupJob
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to decode dedup job %s: %w", job.JobID, err)
+		}
+
+		record.Job = job
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode dedup job: %w", err)
+		}
+		return bucket.Put([]byte(job.JobID), data)
+	})
+}
+
+// Job returns the stored snapshot of the job
+func (bs *BoltDedupStore) Job(jobID string) (*models.ConversionJob, error) {
+	var record dedupJob
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(dedupJobsBucket).Get([]byte(jobID))
+		if value == nil {
+			return ErrJobNotFound
+		}
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to decode dedup job %s: %w", jobID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record.Job, nil
+}
+
+// Prune deletes expired entries and job snapshots
+func (bs *BoltDedupStore) Prune(now time.Time) (int, error) {
+	removed := 0
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(dedupBucket).Cursor()
+		for key, value := cursor.First(); key != nil; {
+			var entry dedupEntry
+			if json.Unmarshal(value, &entry) != nil || !entry.ExpiresAt.After(now) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				removed++
+				// Delete moves the cursor to the next item
+				key, value = cursor.Seek(key)
+				continue
+			}
+			key, value = cursor.Next()
+		}
+
+		cursor = tx.Bucket(dedupJobsBucket).Cursor()
+		for key, value := cursor.First(); key != nil; {
+			var record dedupJob
+			if json.Unmarshal(value, &record) != nil || !record.ExpiresAt.After(now) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				key, value = cursor.Seek(key)
+				continue
+			}
+			key, value = cursor.Next()
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// Close closes the underlying database file
+func (bs *BoltDedupStore) Close() error {
+	return bs.db.Close()
+}
+
+// MemoryDedupStore implements DedupStore in memory. Keys do not survive
+// restarts; it is intended for tests and deployments without a volume.
+type MemoryDedupStore struct {
+	mu      sync.Mutex
+	entries map[string]dedupEntry
+	jobs    map[string]dedupJob
+}
+
+// NewMemoryDedupStore creates an empty in-memory dedup store
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		entries: make(map[string]dedupEntry),
+		jobs:    make(map[string]dedupJob),
+	}
+}
+
+// Claim checks and records the keys under the store lock
+func (ms *MemoryDedupStore) Claim(keys []string, jobID string, expiresAt time.Time) (string, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		if entry, ok := ms.entries[key]; ok && entry.ExpiresAt.After(now) {
+			return entry.JobID, false, nil
+		}
+	}
+	for _, key := range keys {
+		ms.entries[key] = dedupEntry{JobID: jobID, ExpiresAt: expiresAt}
+	}
+	ms.jobs[jobID] = dedupJob{Job: &models.ConversionJob{JobID: jobID}, ExpiresAt: expiresAt}
+	return "", true, nil
+}
+
+// Release deletes the keys held by jobID
+func (ms *MemoryDedupStore) Release(keys []string, jobID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, key := range keys {
+		if entry, ok := ms.entries[key]; ok && entry.JobID == jobID {
+			delete(ms.entries, key)
+		}
+	}
+	delete(ms.jobs, jobID)
+	return nil
+}
+
+// Record replaces the snapshot of the job, keeping its expiry
+func (ms *MemoryDedupStore) Record(job *models.ConversionJob) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if record, ok := ms.jobs[job.JobID]; ok {
+		record.Job = cloneJob(job)
+		ms.jobs[job.JobID] = record
+	}
+	return nil
+}
+
+// Job returns a copy of the job's snapshot
+func (ms *MemoryDedupStore) Job(jobID string) (*models.ConversionJob, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	record, ok := ms.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return cloneJob(record.Job), nil
+}
+
+// Prune deletes expired entries and job snapshots
+func (ms *MemoryDedupStore) Prune(now time.Time) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	removed := 0
+	for key, entry := range ms.entries {
+		if !entry.ExpiresAt.After(now) {
+			delete(ms.entries, key)
+			removed++
+		}
+	}
+	for jobID, record := range ms.jobs {
+		if !record.ExpiresAt.After(now) {
+			delete(ms.jobs, jobID)
+		}
+	}
+	return removed, nil
+}
+
+// Close is a no-op for the in-memory store
+func (ms *MemoryDedupStore) Close() error {
+	return nil
+}
+
+// SubmitJobOnce submits a job unless one of the deduplication keys was
+// already used within the configured TTL, in which case the job created for
+// that key is returned with duplicate true, from the dedup store's snapshot
+// if it has left the registry. Without keys it behaves like SubmitJob. If the submission fails the
+// keys are released, so a redelivery can try again.
+func (w *Worker) SubmitJobOnce(job *models.ConversionJob, keys ...string) (*models.ConversionJob, bool, error) {
+	if len(keys) == 0 {
+		if err := w.SubmitJob(job); err != nil {
+			return nil, false, err
+		}
+		submitted, err := w.GetJob(job.JobID)
+		return submitted, false, err
+	}
+
+	if w.Draining() {
+		return nil, false, ErrDraining
+	}
+	if job.JobID == "" {
+		job.JobID = GenerateJobID()
+	}
+
+	existing, err := w.claimDedupKeys(job.JobID, keys)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		slog.Info("Duplicate job submission, returning existing job",
+			"jobId", existing.JobID,
+			"keys", keys,
+		)
+		return existing, true, nil
+	}
+
+	if err := w.SubmitJob(job); err != nil {
+		if releaseErr := w.dedup.Release(keys, job.JobID); releaseErr != nil {
+			slog.Warn("Failed to release dedup keys of rejected job", "jobId", job.JobID, "error", releaseErr)
+		}
+		return nil, false, err
+	}
+
+	submitted, err := w.GetJob(job.JobID)
+	if err == nil {
+		w.recordDedupJob(submitted)
+	}
+	return submitted, false, err
+}
+
+// claimDedupKeys claims keys for jobID and returns nil, or returns the job
+// that already holds one of them. A key counts as held until it expires,
+// even if its job has since left the registry.
+func (w *Worker) claimDedupKeys(jobID string, keys []string) (*models.ConversionJob, error) {
+	ttl := time.Duration(w.config.Processing.Dedup.TTLMinutes) * time.Minute
+
+	existingJobID, claimed, err := w.dedup.Claim(keys, jobID, time.Now().Add(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicate job: %w", err)
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	existing, err := w.GetJob(existingJobID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, ErrJobNotFound) {
+		return nil, err
+	}
+
+	// The job was pruned from the registry or lost in a restart
+	existing, err = w.dedup.Job(existingJobID)
+	if errors.Is(err, ErrJobNotFound) {
+		slog.Warn("No snapshot of the job holding dedup keys; returning its ID only",
+			"jobId", existingJobID,
+			"keys", keys,
+		)
+		return &models.ConversionJob{JobID: existingJobID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load duplicate job %s: %w", existingJobID, err)
+	}
+	return existing, nil
+}
+
+// recordDedupJob updates the dedup store's snapshot of a job that holds
+// dedup keys, so duplicates resolve to its latest state after it leaves
+// the registry
+func (w *Worker) recordDedupJob(job *models.ConversionJob) {
+	if err := w.dedup.Record(job); err != nil {
+		slog.Warn("Failed to record job for deduplication", "jobId", job.JobID, "error", err)
+	}
+}
+
+// pruneDedupKeys periodically removes expired deduplication keys
+func (w *Worker) pruneDedupKeys() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(dedupPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			removed, err := w.dedup.Prune(now)
+			if err != nil {
+				slog.Warn("Failed to prune dedup keys", "error", err)
+				continue
+			}
+			if removed > 0 {
+				slog.Debug("Pruned expired dedup keys", "count", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestBoltDedupStore_ClaimExpireAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.db")
+
+	store, err := NewBoltDedupStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open dedup store: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	eventKey := EventGridDedupKey("event-1")
+	sourceKey := SourceDedupKey("https://example.com/in.mp4", "0x8D4")
+
+	if _, claimed, err := store.Claim([]string{eventKey, sourceKey}, "job-1", expiresAt); err != nil || !claimed {
+		t.Fatalf("Expected first claim to succeed, got claimed=%v err=%v", claimed, err)
+	}
+
+	// A new event for the same blob version is a duplicate through its source key
+	existing, claimed, err := store.Claim([]string{EventGridDedupKey("event-2"), sourceKey}, "job-2", expiresAt)
+	if err != nil || claimed || existing != "job-1" {
+		t.Fatalf("Expected duplicate of job-1, got existing=%q claimed=%v err=%v", existing, claimed, err)
+	}
+
+	// Expired keys no longer match and are pruned
+	if _, claimed, _ := store.Claim([]string{"stale"}, "job-3", time.Now().Add(-time.Minute)); !claimed {
+		t.Fatal("Expected claim of new key to succeed")
+	}
+	if _, claimed, _ := store.Claim([]string{"stale"}, "job-4", expiresAt); !claimed {
+		t.Error("Expected expired key to be claimable again")
+	}
+	if err := store.Release([]string{"stale"}, "job-3"); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if removed, err := store.Prune(time.Now().Add(2 * time.Hour)); err != nil || removed != 3 {
+		t.Errorf("Expected 3 pruned keys, got %d (err=%v)", removed, err)
+	}
+
+	if _, claimed, _ := store.Claim([]string{eventKey}, "job-5", expiresAt); !claimed {
+		t.Fatal("Expected pruned key to be claimable")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close dedup store: %v", err)
+	}
+
+	// Reopen to verify keys survive a restart
+	store, err = NewBoltDedupStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen dedup store: %v", err)
+	}
+	defer store.Close()
+
+	if existing, claimed, _ := store.Claim([]string{eventKey}, "job-6", expiresAt); claimed || existing != "job-5" {
+		t.Errorf("Expected persisted key for job-5, got existing=%q claimed=%v", existing, claimed)
+	}
+}
+
+func TestSubmitJobOnce_ReturnsExistingJob(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     1,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+
+	key := IdempotencyDedupKey("upload-42")
+	first, duplicate, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-1"}, key)
+	if err != nil || duplicate {
+		t.Fatalf("Expected new job, got duplicate=%v err=%v", duplicate, err)
+	}
+
+	second, duplicate, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-1"}, key)
+	if err != nil || !duplicate || second.JobID != first.JobID {
+		t.Fatalf("Expected duplicate of %s, got %+v duplicate=%v err=%v", first.JobID, second, duplicate, err)
+	}
+
+	// A rejected submission releases its key so a redelivery can succeed later
+	otherKey := IdempotencyDedupKey("upload-43")
+	if _, _, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-2"}, otherKey); err == nil {
+		t.Fatal("Expected the full queue to reject the job")
+	}
+	if _, claimed, _ := w.dedup.Claim([]string{otherKey}, "probe", time.Now().Add(time.Minute)); !claimed {
+		t.Error("Expected key of rejected job to be released")
+	}
+}
+
+func TestSubmitJobOnce_DuplicateOutlivesRegistry(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "bolt", Path: filepath.Join(dir, "dedup.db"), TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	}
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+
+	keys := []string{IdempotencyDedupKey("upload-42"), SourceDedupKey("https://example.com/video.mp4", "abc123")}
+	first, _, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-1"}, keys...)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	w.transitionJob(first.JobID, func(job *models.ConversionJob) {
+		job.Status.State = models.JobStateCompleted
+		job.Status.CompletedAt = time.Now()
+	})
+
+	// The finished job is pruned from the registry while its keys are unexpired
+	w.registry.Remove(first.JobID)
+
+	second, duplicate, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-1"}, keys[1])
+	if err != nil || !duplicate || second.JobID != first.JobID || second.Status.State != models.JobStateCompleted {
+		t.Fatalf("Expected completed duplicate of %s, got %+v duplicate=%v err=%v", first.JobID, second, duplicate, err)
+	}
+
+	// And after a restart
+	w.cancel()
+	w.wg.Wait()
+	w.store.Close()
+	if err := w.dedup.Close(); err != nil {
+		t.Fatalf("Failed to close dedup store: %v", err)
+	}
+
+	w, err = New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to recreate worker: %v", err)
+	}
+	defer w.dedup.Close()
+
+	third, duplicate, err := w.SubmitJobOnce(&models.ConversionJob{VideoID: "video-1"}, keys[0])
+	if err != nil || !duplicate || third.JobID != first.JobID || third.Status.State != models.JobStateCompleted {
+		t.Errorf("Expected completed duplicate of %s after restart, got %+v duplicate=%v err=%v", first.JobID, third, duplicate, err)
+	}
+	if w.scheduler.len() != 0 {
+		t.Errorf("Expected no job to be queued, got %d", w.scheduler.len())
+	}
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// destinationPlaceholder matches {name} placeholders in destination templates
+var destinationPlaceholder = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// destinationVars returns the placeholder values available to a destination
+// template. Job metadata keys are available by name; the built-in variables
+// take precedence over metadata with the same key.
+func destinationVars(job *models.ConversionJob, outputName, profile string) map[string]string {
+	vars := make(map[string]string, len(job.Metadata)+6)
+	for key, value := range job.Metadata {
+		vars[key] = value
+	}
+
+	vars["videoId"] = job.VideoID
+	vars["jobId"] = job.JobID
+	vars["template"] = job.Template
+	vars["output"] = outputName
+	vars["profile"] = profile
+	vars["date"] = job.CreatedAt.Format("2006-01-02")
+
+	return vars
+}
+
+// expandDestination replaces {name} placeholders in a destination template.
+// Unknown placeholders are an error so misconfigured templates never upload
+// to unexpected locations. Values come from clients, so a value that is not a
+// single path segment is an error too; it could move uploads out of the
+// template's prefix and over another video's outputs.
+func expandDestination(template string, vars map[string]string) (string, error) {
+	var missing, invalid []string
+
+	expanded := destinationPlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		if value == "." || value == ".." || strings.ContainsAny(value, `/\`) {
+			invalid = append(invalid, fmt.Sprintf("%s=%q", name, value))
+			return match
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown placeholders in destination %q: %s", template, strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		return "", fmt.Errorf("placeholder values in destination %q must be single path segments: %s", template, strings.Join(invalid, ", "))
+	}
+
+	return expanded, nil
+}
+
+// resolveDestinationPath returns the storage path for one output file.
+//
+// relPath is the file's path relative to the output's working directory, so
+// HLS profile subdirectories (e.g. 720p/720p.m3u8) are preserved and playlist
+// references keep resolving after upload. A destination ending in "/" is a
+// directory prefix; otherwise a single-file output is uploaded to exactly that
+// path. When the destination uses {profile}, the profile directory is folded
+// into the expanded prefix instead of being repeated. Without a destination,
+// files are stored under jobId/outputName/.
+func resolveDestinationPath(job *models.ConversionJob, output *config.OutputConfig,
+	file models.OutputFile, relPath string, fileCount int) (string, error) {
+
+	relPath = filepath.ToSlash(relPath)
+
+	var destPath string
+	if output.Destination == "" {
+		destPath = path.Join(job.JobID, output.Name, relPath)
+	} else {
+		prefix, err := expandDestination(output.Destination, destinationVars(job, output.Name, file.Profile))
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case fileCount == 1 && !strings.HasSuffix(output.Destination, "/"):
+			destPath = prefix
+		case file.Profile != "" && strings.Contains(output.Destination, "{profile}"):
+			destPath = path.Join(prefix, strings.TrimPrefix(relPath, file.Profile+"/"))
+		default:
+			destPath = path.Join(prefix, relPath)
+		}
+	}
+
+	destPath = strings.TrimPrefix(path.Clean("/"+destPath), "/")
+	if destPath == "" || destPath == "." {
+		return "", fmt.Errorf("destination for %s resolved to an empty path", relPath)
+	}
+
+	return destPath, nil
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestResolveDestinationPath(t *testing.T) {
+	job := &models.ConversionJob{
+		JobID:     "job-1",
+		VideoID:   "video-1",
+		Template:  "default",
+		Metadata:  map[string]string{"tenant": "acme"},
+		CreatedAt: time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		destination string
+		file        models.OutputFile
+		relPath     string
+		fileCount   int
+		expected    string
+	}{
+		{"default layout preserves subdirectories", "", models.OutputFile{Profile: "720p"}, "720p/720p.m3u8", 3, "job-1/hls/720p/720p.m3u8"},
+		{"directory prefix", "vod/{videoId}/hls/", models.OutputFile{}, "master.m3u8", 3, "vod/video-1/hls/master.m3u8"},
+		{"directory prefix keeps profile dirs", "vod/{videoId}/hls/", models.OutputFile{Profile: "720p"}, "720p/720p_001.ts", 3, "vod/video-1/hls/720p/720p_001.ts"},
+		{"exact single file path", "vod/{videoId}/progressive/720p.mp4", models.OutputFile{Profile: "720p"}, "720p.mp4", 1, "vod/video-1/progressive/720p.mp4"},
+		{"profile placeholder folds profile dir", "vod/{videoId}/{profile}/", models.OutputFile{Profile: "720p"}, "720p/720p.m3u8", 3, "vod/video-1/720p/720p.m3u8"},
+		{"metadata and date placeholders", "{tenant}/{date}/{jobId}/", models.OutputFile{}, "master.m3u8", 2, "acme/2025-03-14/job-1/master.m3u8"},
+		{"parent references cannot escape", "../{videoId}/", models.OutputFile{}, "master.m3u8", 2, "video-1/master.m3u8"},
+	}
+
+	for _, test := range tests {
+		output := &config.OutputConfig{Name: "hls", Destination: test.destination}
+		got, err := resolveDestinationPath(job, output, test.file, test.relPath, test.fileCount)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.name, got, test.expected)
+		}
+	}
+
+	output := &config.OutputConfig{Name: "hls", Destination: "vod/{unknown}/"}
+	if _, err := resolveDestinationPath(job, output, models.OutputFile{}, "master.m3u8", 2); err == nil {
+		t.Error("Expected error for unknown placeholder")
+	}
+}
+
+func TestResolveDestinationPath_RejectsPathsInValues(t *testing.T) {
+	output := &config.OutputConfig{Name: "hls", Destination: "vod/{tenant}/{videoId}/"}
+
+	for _, value := range []string{"../other-video", "a/b", `a\b`, "..", "."} {
+		for _, job := range []*models.ConversionJob{
+			{JobID: "job-1", VideoID: value, Metadata: map[string]string{"tenant": "acme"}},
+			{JobID: "job-1", VideoID: "video-1", Metadata: map[string]string{"tenant": value}},
+		} {
+			if got, err := resolveDestinationPath(job, output, models.OutputFile{}, "master.m3u8", 2); err == nil {
+				t.Errorf("Expected error for videoId %q and tenant %q, got %s", job.VideoID, job.Metadata["tenant"], got)
+			}
+		}
+	}
+
+	// Dots inside a segment are fine
+	job := &models.ConversionJob{JobID: "job-1", VideoID: "video..1.v2", Metadata: map[string]string{"tenant": "acme"}}
+	if got, err := resolveDestinationPath(job, output, models.OutputFile{}, "master.m3u8", 2); err != nil || got != "vod/acme/video..1.v2/master.m3u8" {
+		t.Errorf("Expected vod/acme/video..1.v2/master.m3u8, got %s (err=%v)", got, err)
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// ErrDraining is returned when a job is submitted while the worker drains
+var ErrDraining = errors.New("service is draining and not accepting jobs")
+
+// Draining reports whether the worker has started draining
+func (w *Worker) Draining() bool {
+	return w.draining.Load()
+}
+
+// RunningJobs returns the number of jobs being processed
+func (w *Worker) RunningJobs() int {
+	w.runningMu.Lock()
+	defer w.runningMu.Unlock()
+	return len(w.running)
+}
+
+// Drain prepares the worker for shutdown without interrupting work in
+// progress. New submissions are refused with ErrDraining, workers stop
+// taking jobs, and queued jobs and jobs waiting to be retried are deferred:
+// with a durable job store they stay pending and resume on the next start,
+// otherwise they are cancelled so their submitters can resubmit elsewhere. Drain then waits for running jobs
+// to finish and returns ctx's error if its deadline passes first; those jobs
+// are interrupted when the worker is stopped and retried after a restart.
+// Calling Drain again waits for the same drain.
+func (w *Worker) Drain(ctx context.Context) error {
+	if w.draining.CompareAndSwap(false, true) {
+		slog.Info("Draining worker",
+			"runningJobs", w.RunningJobs(),
+			"queuedJobs", w.scheduler.len(),
+		)
+		w.stopIntake()
+		w.deferQueuedJobs()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("Worker drained")
+		return nil
+	case <-ctx.Done():
+		slog.Warn("Drain deadline reached, running jobs will be interrupted",
+			"runningJobs", w.RunningJobs(),
+		)
+		return ctx.Err()
+	}
+}
+
+// durableJobStore reports whether pending jobs survive a restart
+func (w *Worker) durableJobStore() bool {
+	return w.config.Processing.JobStore.Type != "memory"
+}
+
+// deferJob hands back a pending job that can't start because the worker is
+// draining: with a durable job store it stays pending for the next start,
+// otherwise it is cancelled
+func (w *Worker) deferJob(jobID string) {
+	durable := w.durableJobStore()
+	w.transitionJob(jobID, func(job *models.ConversionJob) {
+		if durable {
+			job.Status.Message = "Deferred by drain; resumes when the service restarts"
+			return
+		}
+		job.Status.State = models.JobStateCancelled
+		job.Status.Message = "Service drained before the job started; resubmit it"
+		job.Status.CompletedAt = time.Now()
+	})
+}
+
+// deferQueuedJobs takes every waiting job out of the scheduler
+func (w *Worker) deferQueuedJobs() {
+	entries := w.scheduler.removeAll()
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, entry := range entries {
+		w.deferJob(entry.job.JobID)
+	}
+
+	if w.durableJobStore() {
+		slog.Info("Deferred queued jobs to the job store", "count", len(entries))
+	} else {
+		slog.Warn("Cancelled queued jobs; the memory job store cannot keep them across restarts",
+			"count", len(entries))
+	}
+}
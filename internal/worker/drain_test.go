@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestDrain_DefersQueuedJobsAndRefusesNewOnes(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+
+	// The pool is not started, so the job stays queued
+	job := &models.ConversionJob{VideoID: "video-1", Template: "default"}
+	if err := w.SubmitJob(job); err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain with no running jobs failed: %v", err)
+	}
+	if !w.Draining() {
+		t.Error("Expected worker to report draining")
+	}
+	if w.QueueLength() != 0 {
+		t.Errorf("Expected queue to be emptied, %d jobs left", w.QueueLength())
+	}
+
+	// The memory store cannot keep queued jobs, so they are handed back
+	deferred, err := w.GetJob(job.JobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if deferred.Status.State != models.JobStateCancelled {
+		t.Errorf("Expected queued job to be cancelled, got %s", deferred.Status.State)
+	}
+
+	if err := w.SubmitJob(&models.ConversionJob{VideoID: "video-2"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining, got %v", err)
+	}
+}
+
+func TestDrain_DefersPendingRetries(t *testing.T) {
+	for _, storeType := range []string{"memory", "bolt"} {
+		t.Run(storeType, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := New(&config.Config{
+				Processing: config.ProcessingConfig{
+					MaxConcurrentJobs: 1,
+					MaxQueuedJobs:     10,
+					TempDir:           dir,
+					JobStore:          config.JobStoreConfig{Type: storeType, Path: filepath.Join(dir, "jobs.db")},
+					DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+					Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+				},
+				Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+				FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+				JobTemplates: config.JobTemplatesConfig{"default": {}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create worker: %v", err)
+			}
+			defer func() {
+				w.cancel()
+				w.wg.Wait()
+				w.store.Close()
+			}()
+
+			// A failed attempt waiting out its backoff
+			nextRetry := time.Now().Add(time.Hour)
+			job := &models.ConversionJob{JobID: "job-1", Template: "default", Attempts: 1,
+				Status: models.JobStatus{State: models.JobStatePending, NextRetryAt: nextRetry}}
+			if err := w.registry.Add(job); err != nil {
+				t.Fatalf("Failed to add job: %v", err)
+			}
+			w.scheduleRetry(job.JobID, time.Hour)
+
+			if err := w.Drain(context.Background()); err != nil {
+				t.Fatalf("Drain failed: %v", err)
+			}
+
+			expected := models.JobStateCancelled
+			if storeType == "bolt" {
+				expected = models.JobStatePending
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				deferred, _ := w.GetJob(job.JobID)
+				if deferred.Status.State == expected && strings.Contains(deferred.Status.Message, "drain") {
+					if !deferred.Status.NextRetryAt.Equal(nextRetry) {
+						t.Errorf("Expected retry time to be kept, got %v", deferred.Status.NextRetryAt)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected the retry to be deferred as %s, got %+v", expected, deferred.Status)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if w.QueueLength() != 0 {
+				t.Errorf("Expected no queued jobs after drain, got %d", w.QueueLength())
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/matt-primrose/video-converter-service/internal/config"
 	"github.com/matt-primrose/video-converter-service/internal/storage"
@@ -32,26 +33,57 @@ func (w *Worker) downloadSourceFile(ctx context.Context, job *models.ConversionJ
 	}
 
 	// Use storage interface to download the file
-	return downloadStorage.DownloadFile(ctx, sourceURI, job.JobID)
+	return downloadStorage.DownloadFile(ctx, sourceURI, job.JobID, "source")
 }
 
-// validateSourceFile performs basic validation on the source file
-func (w *Worker) validateSourceFile(filePath string) error {
+// downloadCaptionFiles downloads the job's sidecar caption files into the job
+// temp directory as caption-<index> next to the source
+func (w *Worker) downloadCaptionFiles(ctx context.Context, job *models.ConversionJob) ([]transcoder.SidecarCaption, error) {
+	var captions []transcoder.SidecarCaption
+	for i, caption := range job.Captions {
+		slog.Info("Downloading caption file",
+			"jobId", job.JobID,
+			"captionUri", caption.URI,
+			"language", caption.Language,
+		)
+
+		downloadStorage, err := storage.NewDownloadOnlyStorage(strings.ToLower(caption.Type), w.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create download storage: %w", err)
+		}
+
+		path, err := downloadStorage.DownloadFile(ctx, caption.URI, job.JobID, fmt.Sprintf("caption-%d", i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to download caption file %s: %w", caption.URI, err)
+		}
+
+		captions = append(captions, transcoder.SidecarCaption{
+			Path:     path,
+			Language: caption.Language,
+			Label:    caption.Label,
+			Default:  caption.Default,
+		})
+	}
+	return captions, nil
+}
+
+// validateSourceFile performs basic validation on the source file and returns its size
+func (w *Worker) validateSourceFile(filePath string) (int64, error) {
 	// Check file exists and is readable
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return fmt.Errorf("cannot access source file: %w", err)
+		return 0, fmt.Errorf("cannot access source file: %w", err)
 	}
 
 	// Check file is not empty
 	if fileInfo.Size() == 0 {
-		return fmt.Errorf("source file is empty")
+		return 0, fmt.Errorf("source file is empty")
 	}
 
 	// Check file size doesn't exceed limits
 	maxSizeGB := int64(w.config.Processing.MaxTempDiskGB)
 	if maxSizeGB > 0 && fileInfo.Size() > maxSizeGB*1024*1024*1024 {
-		return fmt.Errorf("source file size exceeds maximum allowed (%dGB)", maxSizeGB)
+		return 0, fmt.Errorf("source file size exceeds maximum allowed (%dGB)", maxSizeGB)
 	}
 
 	slog.Info("Source file validation passed",
@@ -59,25 +91,43 @@ func (w *Worker) validateSourceFile(filePath string) error {
 		"size", fileInfo.Size(),
 	)
 
-	return nil
+	return fileInfo.Size(), nil
 }
 
-// uploadOutputFiles uploads the converted files to storage using storage interface
-func (w *Worker) uploadOutputFiles(ctx context.Context, job *models.ConversionJob, result *transcoder.TranscodeResult) error {
+// uploadOutputFiles uploads the converted files to storage using storage interface.
+// Destination paths are resolved from each output's destination template, and
+// the result's file paths are updated to their storage locations. Each
+// destination is recorded on run before its upload starts, so a cancelled
+// job also removes what an interrupted upload left behind.
+func (w *Worker) uploadOutputFiles(ctx context.Context, job *models.ConversionJob,
+	template *config.JobTemplate, result *transcoder.TranscodeResult, run *runningJob) error {
+
 	slog.Info("Uploading output files",
 		"jobId", job.JobID,
 		"outputCount", len(result.Outputs),
 		"storageType", w.outputStorage.GetType(),
 	)
 
-	// Build file map for upload
-	fileMap := make(map[string]string)
+	jobTempDir := filepath.Join(w.config.Processing.TempDir, job.JobID)
+
+	// Map local files to their destinations
+	destPaths := make(map[string]string)
 
 	for _, output := range result.Outputs {
+		outputConfig := findOutputConfig(template, output.Name)
+		outputDir := filepath.Join(jobTempDir, output.Name)
+
 		for _, file := range output.Files {
-			// Create destination path: jobId/outputName/filename
-			destPath := filepath.Join(job.JobID, output.Name, filepath.Base(file.Path))
-			fileMap[file.Path] = destPath
+			relPath, err := filepath.Rel(outputDir, file.Path)
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				relPath = filepath.Base(file.Path)
+			}
+
+			destPath, err := resolveDestinationPath(job, outputConfig, file, relPath, len(output.Files))
+			if err != nil {
+				return fmt.Errorf("failed to resolve destination for output '%s': %w", output.Name, err)
+			}
+			destPaths[file.Path] = destPath
 
 			slog.Debug("Mapping file for upload",
 				"jobId", job.JobID,
@@ -87,41 +137,79 @@ func (w *Worker) uploadOutputFiles(ctx context.Context, job *models.ConversionJo
 		}
 	}
 
-	// Upload all files using storage interface
-	if err := w.outputStorage.UploadFiles(ctx, fileMap); err != nil {
-		return fmt.Errorf("failed to upload files via storage interface: %w", err)
+	// Upload one file at a time so partial uploads are known
+	for sourcePath, destPath := range destPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		run.recordUpload(destPath)
+		if err := w.outputStorage.UploadFile(ctx, sourcePath, destPath); err != nil {
+			return fmt.Errorf("failed to upload file %s: %w", sourcePath, err)
+		}
+	}
+
+	// Report storage locations rather than temp paths that are about to be cleaned up
+	for i := range result.Outputs {
+		for j := range result.Outputs[i].Files {
+			file := &result.Outputs[i].Files[j]
+			file.Path = destPaths[file.Path]
+
+			fileURL, err := w.outputStorage.GetFileURL(file.Path)
+			if err != nil {
+				slog.Warn("Failed to resolve output file URL", "jobId", job.JobID, "path", file.Path, "error", err)
+				continue
+			}
+			file.URL = fileURL
+		}
 	}
 
 	slog.Info("Successfully uploaded all output files",
 		"jobId", job.JobID,
-		"fileCount", len(fileMap),
+		"fileCount", len(destPaths),
 		"storageType", w.outputStorage.GetType(),
 	)
 
 	return nil
 }
 
-// sendNotifications sends completion notifications (placeholder)
-func (w *Worker) sendNotifications(ctx context.Context, job *models.ConversionJob,
-	template *config.JobTemplate, result *transcoder.TranscodeResult) error {
-
-	if template.Notifications.WebhookURL == "" {
-		slog.Debug("No webhook configured for notifications", "jobId", job.JobID)
-		return nil
+// findOutputConfig returns the template output with the given name, or a
+// bare config carrying only the name if the template does not define it
+func findOutputConfig(template *config.JobTemplate, name string) *config.OutputConfig {
+	for i := range template.Outputs {
+		if template.Outputs[i].Name == name {
+			return &template.Outputs[i]
+		}
 	}
+	return &config.OutputConfig{Name: name}
+}
 
-	if !template.Notifications.OnComplete {
-		slog.Debug("Completion notifications disabled", "jobId", job.JobID)
-		return nil
-	}
+// buildConversionResult converts a transcoder result into the job's final conversion result
+func buildConversionResult(job *models.ConversionJob, result *transcoder.TranscodeResult,
+	sourceSize int64, processingTime, downloadTime, uploadTime time.Duration) *models.ConversionResult {
 
-	slog.Info("Sending completion notification",
-		"jobId", job.JobID,
-		"webhookUrl", template.Notifications.WebhookURL,
-	)
+	stats := models.ConversionStatistics{
+		SourceFileSize: sourceSize,
+		ProcessingTime: processingTime,
+		DownloadTime:   downloadTime,
+		UploadTime:     uploadTime,
+		FFmpegTime:     result.Duration,
+	}
 
-	// TODO: Implement actual webhook notification
-	// This would typically involve sending an HTTP POST with job results
+	for _, output := range result.Outputs {
+		for _, file := range output.Files {
+			stats.TotalOutputSize += file.Size
+		}
+		if len(output.Files) > 0 {
+			stats.ProfilesProcessed++
+		}
+	}
 
-	return nil
+	return &models.ConversionResult{
+		JobID:      job.JobID,
+		VideoID:    job.VideoID,
+		Outputs:    result.Outputs,
+		Duration:   processingTime,
+		Statistics: stats,
+		CreatedAt:  time.Now(),
+	}
 }
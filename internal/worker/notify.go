@@ -0,0 +1,235 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+const (
+	defaultNotificationRetries = 3
+	defaultNotificationTimeout = 10 * time.Second
+	notificationInitialBackoff = time.Second
+	notificationMaxBackoff     = 30 * time.Second
+)
+
+// Webhook request headers. The delivery ID is stable across retries so
+// receivers can discard duplicates; the signature covers "<timestamp>.<body>".
+const (
+	NotificationEventHeader     = "X-Video-Converter-Event"
+	NotificationDeliveryHeader  = "X-Video-Converter-Delivery"
+	NotificationTimestampHeader = "X-Video-Converter-Timestamp"
+	NotificationSignatureHeader = "X-Video-Converter-Signature"
+)
+
+// webhookStatusError is returned when a webhook responds with a non-2xx status
+type webhookStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *webhookStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether the receiver may accept the notification later
+func (e *webhookStatusError) retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// notifyJob sends the template's webhook notification for a finished job in
+// the background. Notification failures are logged and never affect the job.
+func (w *Worker) notifyJob(job *models.ConversionJob, template *config.JobTemplate) {
+	settings := template.Notifications
+	if settings.WebhookURL == "" {
+		slog.Debug("No webhook configured for notifications", "jobId", job.JobID)
+		return
+	}
+
+	var event string
+	switch job.Status.State {
+	case models.JobStateCompleted:
+		if !settings.OnComplete {
+			slog.Debug("Completion notifications disabled", "jobId", job.JobID)
+			return
+		}
+		event = models.NotificationJobCompleted
+	case models.JobStateFailed:
+		if !settings.OnFailure {
+			slog.Debug("Failure notifications disabled", "jobId", job.JobID)
+			return
+		}
+		event = models.NotificationJobFailed
+	default:
+		return
+	}
+
+	notification := &models.JobNotification{
+		Event:         event,
+		JobID:         job.JobID,
+		CorrelationID: job.CorrelationID,
+		VideoID:       job.VideoID,
+		Template:      job.Template,
+		Metadata:      job.Metadata,
+		Status:        job.Status,
+		Result:        job.Result,
+		Error:         job.Status.Error,
+		Timestamp:     time.Now(),
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := w.sendNotification(w.ctx, settings, notification); err != nil {
+			slog.Warn("Failed to send notification",
+				"jobId", job.JobID,
+				"event", event,
+				"webhookUrl", settings.WebhookURL,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// notifyMissingTemplate sends the failure notification of a job whose
+// template no longer exists through the default template's webhook, the only
+// one left that can speak for it
+func (w *Worker) notifyMissingTemplate(job *models.ConversionJob) {
+	fallback, exists := w.config.JobTemplates["default"]
+	if !exists || fallback.Notifications.WebhookURL == "" {
+		slog.Warn("No failure notification sent; the job's template is missing and the default template has no webhook",
+			"jobId", job.JobID,
+			"template", job.Template,
+		)
+		return
+	}
+
+	slog.Info("Sending failure notification through the default template's webhook",
+		"jobId", job.JobID,
+		"template", job.Template,
+	)
+	w.notifyJob(job, &fallback)
+}
+
+// sendNotification posts a notification to the webhook, retrying transient
+// failures with exponential backoff
+func (w *Worker) sendNotification(ctx context.Context, settings config.NotificationConfig,
+	notification *models.JobNotification) error {
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	maxRetries := settings.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultNotificationRetries
+	}
+	timeout := time.Duration(settings.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = defaultNotificationTimeout
+	}
+	deliveryID := notification.JobID + ":" + notification.Event
+
+	backoff := notificationInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.postNotification(ctx, settings, timeout, deliveryID, notification.Event, body)
+		if err == nil {
+			slog.Info("Notification delivered",
+				"jobId", notification.JobID,
+				"event", notification.Event,
+				"attempt", attempt,
+			)
+			return nil
+		}
+
+		var statusErr *webhookStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return err
+		}
+		if attempt > maxRetries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("Notification attempt failed, retrying",
+			"jobId", notification.JobID,
+			"event", notification.Event,
+			"attempt", attempt,
+			"retryIn", backoff,
+			"error", err,
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("notification abandoned: %w", ctx.Err())
+		}
+		backoff = min(backoff*2, notificationMaxBackoff)
+	}
+}
+
+// postNotification performs a single webhook delivery attempt
+func (w *Worker) postNotification(ctx context.Context, settings config.NotificationConfig,
+	timeout time.Duration, deliveryID, event string, body []byte) error {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "video-converter-service")
+	req.Header.Set(NotificationEventHeader, event)
+	req.Header.Set(NotificationDeliveryHeader, deliveryID)
+	req.Header.Set(NotificationTimestampHeader, timestamp)
+	if settings.Secret != "" {
+		req.Header.Set(NotificationSignatureHeader, signNotification(settings.Secret, timestamp, body))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &webhookStatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(snippet))}
+	}
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return nil
+}
+
+// signNotification returns the signature header value for a webhook body:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>"
+func signNotification(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestSendNotification_RetriesAndSigns(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(NotificationTimestampHeader)
+		if got, expected := r.Header.Get(NotificationSignatureHeader), signNotification("s3cret", timestamp, body); got != expected {
+			t.Errorf("Signature mismatch: got %s, expected %s", got, expected)
+		}
+		if got := r.Header.Get(NotificationDeliveryHeader); got != "job-1:job.completed" {
+			t.Errorf("Unexpected delivery ID %s", got)
+		}
+
+		var notification models.JobNotification
+		if err := json.Unmarshal(body, &notification); err != nil {
+			t.Errorf("Invalid notification body: %v", err)
+		}
+		if notification.Event != models.NotificationJobCompleted || notification.Result == nil {
+			t.Errorf("Unexpected notification: %+v", notification)
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := &Worker{httpClient: server.Client()}
+	settings := config.NotificationConfig{WebhookURL: server.URL, Secret: "s3cret", MaxRetries: 2}
+	notification := &models.JobNotification{
+		Event:  models.NotificationJobCompleted,
+		JobID:  "job-1",
+		Result: &models.ConversionResult{JobID: "job-1"},
+	}
+
+	if err := w.sendNotification(context.Background(), settings, notification); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestSendNotification_ClientErrorIsNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	w := &Worker{httpClient: server.Client()}
+	settings := config.NotificationConfig{WebhookURL: server.URL}
+	notification := &models.JobNotification{Event: models.NotificationJobFailed, JobID: "job-1"}
+
+	if err := w.sendNotification(context.Background(), settings, notification); err == nil {
+		t.Fatal("Expected error for rejected notification")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestProcessJob_MissingTemplateNotifiesThroughDefault(t *testing.T) {
+	received := make(chan models.JobNotification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var notification models.JobNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("Invalid notification body: %v", err)
+		}
+		received <- notification
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage: config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:  config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {
+			Notifications: config.NotificationConfig{WebhookURL: server.URL, OnFailure: true},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+	defer func() {
+		w.cancel()
+		w.wg.Wait()
+	}()
+
+	// A job recovered after its template was removed from the configuration
+	job := &models.ConversionJob{JobID: "job-1", Template: "removed", Status: models.JobStatus{State: models.JobStatePending}}
+	if err := w.registry.Add(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	w.processJob(0, job)
+
+	select {
+	case notification := <-received:
+		if notification.Event != models.NotificationJobFailed || notification.JobID != "job-1" || notification.Template != "removed" {
+			t.Errorf("Unexpected notification: %+v", notification)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a failure notification through the default template's webhook")
+	}
+}
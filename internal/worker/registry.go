@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// jobRegistry is a concurrency-safe in-memory record of every submitted job.
+// Finished jobs are retained for a bounded time and count so their status and
+// results can still be queried after processing.
+type jobRegistry struct {
+	mu          sync.RWMutex
+	jobs        map[string]*models.ConversionJob
+	retention   time.Duration
+	maxFinished int
+}
+
+// newJobRegistry creates a job registry with the given retention limits.
+// A zero retention or maxFinished disables that limit.
+func newJobRegistry(retention time.Duration, maxFinished int) *jobRegistry {
+	return &jobRegistry{
+		jobs:        make(map[string]*models.ConversionJob),
+		retention:   retention,
+		maxFinished: maxFinished,
+	}
+}
+
+// Add registers a new job. The registry keeps its own copy of the job.
+func (r *jobRegistry) Add(job *models.ConversionJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.jobs[job.JobID]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.JobID)
+	}
+
+	r.jobs[job.JobID] = cloneJob(job)
+	r.pruneLocked(time.Now())
+	return nil
+}
+
+// Remove drops a job from the registry
+func (r *jobRegistry) Remove(jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, jobID)
+}
+
+// Get returns a snapshot of the job with the given ID
+func (r *jobRegistry) Get(jobID string) (*models.ConversionJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, exists := r.jobs[jobID]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	return cloneJob(job), nil
+}
+
+// List returns snapshots of all jobs ordered by creation time.
+// If states are given, only jobs in one of those states are returned.
+func (r *jobRegistry) List(states ...models.JobState) []*models.ConversionJob {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jobs := make([]*models.ConversionJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		if len(states) > 0 && !containsState(states, job.Status.State) {
+			continue
+		}
+		jobs = append(jobs, cloneJob(job))
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Update applies a mutation to a registered job and returns a snapshot of the result
+func (r *jobRegistry) Update(jobID string, update func(job *models.ConversionJob)) (*models.ConversionJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, exists := r.jobs[jobID]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+
+	update(job)
+	job.Status.UpdatedAt = time.Now()
+
+	if job.Status.State.IsTerminal() {
+		r.pruneLocked(job.Status.UpdatedAt)
+	}
+	return cloneJob(job), nil
+}
+
+// State returns the current state of a job
+func (r *jobRegistry) State(jobID string) (models.JobState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, exists := r.jobs[jobID]
+	if !exists {
+		return "", ErrJobNotFound
+	}
+	return job.Status.State, nil
+}
+
+// Statuses returns the current status of every job keyed by job ID
+func (r *jobRegistry) Statuses() map[string]models.JobStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make(map[string]models.JobStatus, len(r.jobs))
+	for id, job := range r.jobs {
+		statuses[id] = job.Status
+	}
+	return statuses
+}
+
+// pruneLocked removes expired finished jobs, then the oldest finished jobs
+// beyond maxFinished. The caller must hold the write lock.
+func (r *jobRegistry) pruneLocked(now time.Time) {
+	var finished []*models.ConversionJob
+
+	for id, job := range r.jobs {
+		if !job.Status.State.IsTerminal() {
+			continue
+		}
+		if r.retention > 0 && now.Sub(job.Status.CompletedAt) > r.retention {
+			delete(r.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if r.maxFinished > 0 && len(finished) > r.maxFinished {
+		sort.Slice(finished, func(i, j int) bool {
+			return finished[i].Status.CompletedAt.Before(finished[j].Status.CompletedAt)
+		})
+		for _, job := range finished[:len(finished)-r.maxFinished] {
+			delete(r.jobs, job.JobID)
+		}
+	}
+}
+
+// cloneJob returns a copy of the job that is safe to hand out to callers
+func cloneJob(job *models.ConversionJob) *models.ConversionJob {
+	clone := *job
+	if job.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(job.Metadata))
+		for k, v := range job.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	if job.Result != nil {
+		result := *job.Result
+		clone.Result = &result
+	}
+	clone.Captions = append([]models.CaptionSource(nil), job.Captions...)
+	clone.History = append([]models.JobAttempt(nil), job.History...)
+	return &clone
+}
+
+// containsState reports whether state is one of states
+func containsState(states []models.JobState, state models.JobState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
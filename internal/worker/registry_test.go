@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestJobRegistry_AddAndUpdate(t *testing.T) {
+	registry := newJobRegistry(time.Hour, 10)
+
+	job := &models.ConversionJob{
+		JobID:     "job-1",
+		CreatedAt: time.Now(),
+		Status:    models.JobStatus{State: models.JobStatePending},
+	}
+	if err := registry.Add(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	if err := registry.Add(job); !errors.Is(err, ErrJobExists) {
+		t.Errorf("Expected ErrJobExists for duplicate job, got %v", err)
+	}
+
+	// Mutating the caller's copy must not affect the registry
+	job.Status.State = models.JobStateFailed
+	if state, _ := registry.State("job-1"); state != models.JobStatePending {
+		t.Errorf("Expected registry state to be pending, got %s", state)
+	}
+
+	updated, err := registry.Update("job-1", func(job *models.ConversionJob) {
+		job.Status.State = models.JobStateProcessing
+		job.Status.Progress = 0.5
+	})
+	if err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+	if updated.Status.Progress != 0.5 || updated.Status.UpdatedAt.IsZero() {
+		t.Errorf("Expected progress and updatedAt to be set, got %+v", updated.Status)
+	}
+
+	if _, err := registry.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	if jobs := registry.List(models.JobStateProcessing); len(jobs) != 1 {
+		t.Errorf("Expected 1 processing job, got %d", len(jobs))
+	}
+	if jobs := registry.List(models.JobStateCompleted); len(jobs) != 0 {
+		t.Errorf("Expected 0 completed jobs, got %d", len(jobs))
+	}
+}
+
+func TestJobRegistry_Retention(t *testing.T) {
+	registry := newJobRegistry(time.Hour, 2)
+	now := time.Now()
+
+	// Three finished jobs; only the two most recent should be retained
+	for i, id := range []string{"old", "mid", "new"} {
+		registry.Add(&models.ConversionJob{JobID: id, CreatedAt: now})
+		registry.Update(id, func(job *models.ConversionJob) {
+			job.Status.State = models.JobStateCompleted
+			job.Status.CompletedAt = now.Add(time.Duration(i) * time.Minute)
+		})
+	}
+
+	// Running jobs are never pruned
+	registry.Add(&models.ConversionJob{JobID: "running", CreatedAt: now})
+
+	if _, err := registry.Get("old"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected oldest finished job to be pruned, got %v", err)
+	}
+	for _, id := range []string{"mid", "new", "running"} {
+		if _, err := registry.Get(id); err != nil {
+			t.Errorf("Expected job %s to be retained, got %v", id, err)
+		}
+	}
+
+	// Finished jobs past the retention window are pruned
+	registry.Update("mid", func(job *models.ConversionJob) {
+		job.Status.CompletedAt = now.Add(-2 * time.Hour)
+	})
+	if _, err := registry.Get("mid"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected expired job to be pruned, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 30 * time.Second
+	defaultRetryMaxBackoff     = 10 * time.Minute
+)
+
+// retryPolicy is a template's retry configuration with defaults applied
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// newRetryPolicy applies defaults to a template's retry configuration
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:    cfg.MaxAttempts,
+		initialBackoff: time.Duration(cfg.InitialBackoffSeconds) * time.Second,
+		maxBackoff:     time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+	}
+	if policy.maxAttempts == 0 {
+		policy.maxAttempts = defaultRetryMaxAttempts
+	}
+	if policy.initialBackoff == 0 {
+		policy.initialBackoff = defaultRetryInitialBackoff
+	}
+	if policy.maxBackoff == 0 {
+		policy.maxBackoff = defaultRetryMaxBackoff
+	}
+	return policy
+}
+
+// backoff returns the delay before the retry that follows the given attempt
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.initialBackoff
+	for i := 1; i < attempt && delay < p.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.maxBackoff)
+}
+
+// isRetryable classifies a conversion error as transient or permanent.
+// Storage and transcoder errors classify themselves; otherwise network
+// failures are transient and everything else (missing files, invalid
+// input, bad configuration) is permanent.
+func isRetryable(err error) bool {
+	var classified interface{ Retryable() bool }
+	if errors.As(err, &classified) {
+		return classified.Retryable()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	// Connection and DNS failures, and HTTP client timeouts. net.Error itself
+	// is too broad: syscall errors such as ENOENT implement it too.
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	return errors.As(err, &opErr) ||
+		errors.As(err, &dnsErr) ||
+		(errors.As(err, &urlErr) && urlErr.Timeout())
+}
+
+// scheduleRetry re-enqueues a pending job once its retry time is reached.
+// A drain ends the wait early and defers the job like the queued ones.
+func (w *Worker) scheduleRetry(jobID string, delay time.Duration) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-w.intakeCtx.Done():
+		}
+		if w.ctx.Err() != nil {
+			// The job stays pending in the job store and is retried on restart
+			return
+		}
+
+		job, err := w.registry.Get(jobID)
+		if err != nil || job.Status.State != models.JobStatePending {
+			slog.Info("Skipping retry for job that is no longer pending", "jobId", jobID)
+			return
+		}
+
+		if w.Draining() {
+			w.deferJob(jobID)
+			slog.Info("Retry deferred by drain", "jobId", jobID)
+			return
+		}
+
+		w.scheduler.push(job, w.jobTenant(job), true)
+
+		// A drain that emptied the queue before the push missed this job
+		if w.Draining() && w.scheduler.remove(jobID) {
+			w.deferJob(jobID)
+			slog.Info("Retry deferred by drain", "jobId", jobID)
+			return
+		}
+		slog.Info("Job re-queued for retry", "jobId", jobID, "attempt", job.Attempts+1)
+	}()
+}
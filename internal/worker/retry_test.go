@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/internal/storage"
+	"github.com/matt-primrose/video-converter-service/internal/transcoder"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"storage throttling", &storage.StatusError{StatusCode: 503, Err: errors.New("unavailable")}, true},
+		{"storage not found", &storage.StatusError{StatusCode: 404, Err: errors.New("not found")}, false},
+		{"ffmpeg rejected input", &transcoder.FFmpegError{Tool: "ffmpeg", ExitCode: 1}, false},
+		{"ffmpeg killed", &transcoder.FFmpegError{Tool: "ffmpeg", ExitCode: -1}, true},
+		{"download timeout", fmt.Errorf("failed to download source file: %w", context.DeadlineExceeded), true},
+		{"missing local file", &fs.PathError{Op: "stat", Path: "/in.mp4", Err: syscall.ENOENT}, false},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+	}
+
+	for _, test := range tests {
+		wrapped := fmt.Errorf("conversion: %w", test.err)
+		if got := isRetryable(wrapped); got != test.retryable {
+			t.Errorf("%s: got retryable=%v, expected %v", test.name, got, test.retryable)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{InitialBackoffSeconds: 10, MaxBackoffSeconds: 35})
+
+	if policy.maxAttempts != defaultRetryMaxAttempts {
+		t.Errorf("Expected default max attempts %d, got %d", defaultRetryMaxAttempts, policy.maxAttempts)
+	}
+
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 35 * time.Second, 35 * time.Second}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: got backoff %s, expected %s", i+1, got, want)
+		}
+	}
+}
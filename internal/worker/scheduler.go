@@ -0,0 +1,327 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// priorityMetadataKey is the job metadata key that overrides the template priority
+const priorityMetadataKey = "priority"
+
+// scheduledJob is a job waiting in the scheduler
+type scheduledJob struct {
+	job    *models.ConversionJob
+	tenant string
+	seq    uint64 // Submission order, breaks priority ties
+	index  int    // Position in the tenant's heap
+}
+
+// tenantQueue is a heap of a tenant's waiting jobs, highest priority first
+// and in submission order within a priority
+type tenantQueue []*scheduledJob
+
+func (q tenantQueue) Len() int { return len(q) }
+
+func (q tenantQueue) Less(i, j int) bool {
+	return jobBefore(q[i], q[j])
+}
+
+func (q tenantQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *tenantQueue) Push(x any) {
+	entry := x.(*scheduledJob)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *tenantQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// jobBefore orders jobs within a tenant
+func jobBefore(a, b *scheduledJob) bool {
+	if a.job.Priority != b.job.Priority {
+		return a.job.Priority > b.job.Priority
+	}
+	return a.seq < b.seq
+}
+
+// tenantState tracks a tenant's waiting and running jobs
+type tenantState struct {
+	queue      tenantQueue
+	running    int
+	lastServed uint64 // Dispatch counter value when a job of this tenant last started
+}
+
+// tenantTurn is what the scheduler compares when choosing the next tenant
+type tenantTurn struct {
+	priority   int    // Priority of the tenant's next job
+	running    int    // Jobs of the tenant being processed
+	lastServed uint64 // When the tenant was last served
+	seq        uint64 // Submission order of the tenant's next job
+}
+
+// before reports whether tenant a should be served ahead of tenant b: the
+// highest waiting priority wins, then the tenant with fewer running jobs,
+// then the one served least recently, then the one with the oldest job
+func (a tenantTurn) before(b tenantTurn) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if a.running != b.running {
+		return a.running < b.running
+	}
+	if a.lastServed != b.lastServed {
+		return a.lastServed < b.lastServed
+	}
+	return a.seq < b.seq
+}
+
+// jobScheduler holds pending jobs and hands them to workers by priority,
+// sharing workers fairly between tenants so one tenant's backlog cannot
+// starve the others
+type jobScheduler struct {
+	mu       sync.Mutex
+	capacity int
+	tenants  map[string]*tenantState
+	jobs     map[string]*scheduledJob
+	seq      uint64
+	served   uint64
+	wake     chan struct{} // Closed and replaced when a job is pushed
+}
+
+// newJobScheduler creates a scheduler that accepts up to capacity waiting jobs
+func newJobScheduler(capacity int) *jobScheduler {
+	return &jobScheduler{
+		capacity: capacity,
+		tenants:  make(map[string]*tenantState),
+		jobs:     make(map[string]*scheduledJob),
+		wake:     make(chan struct{}),
+	}
+}
+
+// push adds a job for the tenant. New submissions are refused with
+// ErrQueueFull once the backlog is at capacity; jobs that were already
+// accepted (retries, recovered jobs) are always taken back.
+func (s *jobScheduler) push(job *models.ConversionJob, tenant string, accepted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.JobID]; exists {
+		return nil
+	}
+	if !accepted && len(s.jobs) >= s.capacity {
+		return ErrQueueFull
+	}
+
+	state := s.tenants[tenant]
+	if state == nil {
+		state = &tenantState{}
+		s.tenants[tenant] = state
+	}
+
+	s.seq++
+	entry := &scheduledJob{job: job, tenant: tenant, seq: s.seq}
+	heap.Push(&state.queue, entry)
+	s.jobs[job.JobID] = entry
+
+	close(s.wake)
+	s.wake = make(chan struct{})
+	return nil
+}
+
+// remove drops a waiting job, reporting whether it was queued
+func (s *jobScheduler) remove(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.jobs[jobID]
+	if !exists {
+		return false
+	}
+	state := s.tenants[entry.tenant]
+	heap.Remove(&state.queue, entry.index)
+	delete(s.jobs, jobID)
+	s.pruneLocked(entry.tenant)
+	return true
+}
+
+// next blocks until a job is available or ctx is done. The caller must call
+// done with the returned entry once the job has been processed.
+func (s *jobScheduler) next(ctx context.Context) (*scheduledJob, error) {
+	for {
+		s.mu.Lock()
+		if tenant, ok := s.pickLocked(); ok {
+			state := s.tenants[tenant]
+			entry := heap.Pop(&state.queue).(*scheduledJob)
+			delete(s.jobs, entry.job.JobID)
+
+			s.served++
+			state.running++
+			state.lastServed = s.served
+			s.mu.Unlock()
+			return entry, nil
+		}
+		wake := s.wake
+		s.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// done records that a job handed out by next has finished
+func (s *jobScheduler) done(entry *scheduledJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state := s.tenants[entry.tenant]; state != nil {
+		state.running--
+		s.pruneLocked(entry.tenant)
+	}
+}
+
+// pickLocked returns the tenant whose job should run next, or false if no
+// jobs are waiting
+func (s *jobScheduler) pickLocked() (string, bool) {
+	var best string
+	var bestTurn tenantTurn
+	found := false
+	for tenant, state := range s.tenants {
+		if len(state.queue) == 0 {
+			continue
+		}
+		turn := tenantTurn{
+			priority:   state.queue[0].job.Priority,
+			running:    state.running,
+			lastServed: state.lastServed,
+			seq:        state.queue[0].seq,
+		}
+		if !found || turn.before(bestTurn) {
+			best, bestTurn, found = tenant, turn, true
+		}
+	}
+	return best, found
+}
+
+// pruneLocked forgets a tenant with nothing waiting or running
+func (s *jobScheduler) pruneLocked(tenant string) {
+	if state := s.tenants[tenant]; state != nil && len(state.queue) == 0 && state.running <= 0 {
+		delete(s.tenants, tenant)
+	}
+}
+
+// removeAll drops and returns every waiting job, in no particular order
+func (s *jobScheduler) removeAll() []*scheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]*scheduledJob, 0, len(s.jobs))
+	for _, entry := range s.jobs {
+		entries = append(entries, entry)
+	}
+	for tenant, state := range s.tenants {
+		state.queue = nil
+		s.pruneLocked(tenant)
+	}
+	s.jobs = make(map[string]*scheduledJob)
+	return entries
+}
+
+// len returns the number of waiting jobs
+func (s *jobScheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// positions estimates each waiting job's place in line (1 runs next) by
+// replaying the scheduling decisions over the current backlog, assuming
+// running jobs keep running
+func (s *jobScheduler) positions() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type simulatedTenant struct {
+		waiting []*scheduledJob
+		turn    tenantTurn
+	}
+
+	tenants := make([]*simulatedTenant, 0, len(s.tenants))
+	for _, state := range s.tenants {
+		if len(state.queue) == 0 {
+			continue
+		}
+		waiting := append([]*scheduledJob(nil), state.queue...)
+		sort.Slice(waiting, func(i, j int) bool { return jobBefore(waiting[i], waiting[j]) })
+		tenants = append(tenants, &simulatedTenant{
+			waiting: waiting,
+			turn:    tenantTurn{running: state.running, lastServed: state.lastServed},
+		})
+	}
+
+	positions := make(map[string]int, len(s.jobs))
+	served := s.served
+	for position := 1; position <= len(s.jobs); position++ {
+		var best *simulatedTenant
+		for _, tenant := range tenants {
+			if len(tenant.waiting) == 0 {
+				continue
+			}
+			tenant.turn.priority = tenant.waiting[0].job.Priority
+			tenant.turn.seq = tenant.waiting[0].seq
+			if best == nil || tenant.turn.before(best.turn) {
+				best = tenant
+			}
+		}
+
+		positions[best.waiting[0].job.JobID] = position
+		best.waiting = best.waiting[1:]
+		served++
+		best.turn.running++
+		best.turn.lastServed = served
+	}
+	return positions
+}
+
+// jobPriority resolves a job's priority: a "priority" metadata entry wins
+// over the template's priority. Higher values run first.
+func jobPriority(job *models.ConversionJob, templatePriority int) int {
+	if value, ok := job.Metadata[priorityMetadataKey]; ok {
+		if priority, err := strconv.Atoi(value); err == nil {
+			return priority
+		}
+	}
+	return templatePriority
+}
+
+// jobTenant returns the tenant a job is scheduled under
+func (w *Worker) jobTenant(job *models.ConversionJob) string {
+	return job.Metadata[w.config.Processing.TenantMetadataKey]
+}
+
+// withQueuePositions fills in the queue position of waiting jobs
+func (w *Worker) withQueuePositions(jobs ...*models.ConversionJob) {
+	positions := w.scheduler.positions()
+	for _, job := range jobs {
+		if job.Status.State == models.JobStatePending {
+			job.Status.QueuePosition = positions[job.JobID]
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestJobScheduler_PriorityAndFairness(t *testing.T) {
+	s := newJobScheduler(10)
+	push := func(jobID, tenant string, priority int) {
+		t.Helper()
+		if err := s.push(&models.ConversionJob{JobID: jobID, Priority: priority}, tenant, false); err != nil {
+			t.Fatalf("Failed to push %s: %v", jobID, err)
+		}
+	}
+
+	// A bulk import from one tenant, then short clips from another
+	push("bulk-1", "acme", 0)
+	push("bulk-2", "acme", 0)
+	push("bulk-3", "acme", 0)
+	push("clip-1", "globex", 0)
+	push("clip-2", "globex", 0)
+	push("urgent", "acme", 10)
+
+	if err := s.push(&models.ConversionJob{JobID: "overflow"}, "acme", false); err != nil {
+		t.Fatalf("Unexpected error below capacity: %v", err)
+	}
+	if !s.remove("overflow") {
+		t.Fatal("Expected queued job to be removed")
+	}
+
+	expected := []string{"urgent", "clip-1", "bulk-1", "clip-2", "bulk-2", "bulk-3"}
+
+	positions := s.positions()
+	for i, jobID := range expected {
+		if positions[jobID] != i+1 {
+			t.Errorf("Expected %s at position %d, got %d", jobID, i+1, positions[jobID])
+		}
+	}
+
+	// Jobs keep running while later ones are dispatched, as the positions assume
+	for _, jobID := range expected {
+		entry, err := s.next(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to get next job: %v", err)
+		}
+		if entry.job.JobID != jobID {
+			t.Fatalf("Expected %s next, got %s", jobID, entry.job.JobID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected next to return when the context is done, got %v", err)
+	}
+}
+
+func TestJobScheduler_Capacity(t *testing.T) {
+	s := newJobScheduler(1)
+
+	if err := s.push(&models.ConversionJob{JobID: "job-1"}, "", false); err != nil {
+		t.Fatalf("Failed to push job: %v", err)
+	}
+	if err := s.push(&models.ConversionJob{JobID: "job-2"}, "", false); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	// Jobs that were already accepted are taken back regardless
+	if err := s.push(&models.ConversionJob{JobID: "retry"}, "", true); err != nil {
+		t.Errorf("Expected accepted job to be queued, got %v", err)
+	}
+	if s.len() != 2 {
+		t.Errorf("Expected 2 queued jobs, got %d", s.len())
+	}
+}
+
+func TestValidateJob_ClientPriority(t *testing.T) {
+	w := &Worker{config: &config.Config{
+		Processing:   config.ProcessingConfig{MaxClientPriority: 5},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	}}
+	newJob := func(priority string) *models.ConversionJob {
+		job := &models.ConversionJob{
+			VideoID:  "video-1",
+			Source:   models.SourceConfig{URI: "/videos/in.mp4", Type: "local"},
+			Priority: 100,
+		}
+		if priority != "" {
+			job.Metadata = map[string]string{priorityMetadataKey: priority}
+		}
+		return job
+	}
+
+	for priority, valid := range map[string]bool{"": true, "-10": true, "5": true, "6": false, "high": false} {
+		job := newJob(priority)
+		err := w.ValidateJob(job)
+		if valid != (err == nil) {
+			t.Errorf("ValidateJob with priority %q = %v, expected valid %v", priority, err, valid)
+		}
+		if valid && job.Priority != 0 {
+			t.Errorf("Expected the client's priority field to be ignored, got %d", job.Priority)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// JobStore persists submitted jobs so that queued and running work
+// survives service restarts
+type JobStore interface {
+	// Save creates or replaces the stored record of a job
+	Save(job *models.ConversionJob) error
+
+	// Delete removes a job from the store
+	Delete(jobID string) error
+
+	// List returns every stored job
+	List() ([]*models.ConversionJob, error)
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// NewJobStore creates a job store based on configuration
+func NewJobStore(cfg *config.Config) (JobStore, error) {
+	storeConfig := cfg.Processing.JobStore
+
+	switch storeConfig.Type {
+	case "", "bolt":
+		return NewBoltJobStore(storeConfig.Path)
+	case "memory":
+		return NewMemoryJobStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported job store type: %s", storeConfig.Type)
+	}
+}
+
+// jobsBucket is the BoltDB bucket holding serialized jobs keyed by job ID
+var jobsBucket = []byte("jobs")
+
+// BoltJobStore implements JobStore using an embedded BoltDB file
+type BoltJobStore struct {
+	db *bolt.DB
+}
+
+// NewBoltJobStore opens (or creates) a BoltDB job store at the given path
+func NewBoltJobStore(path string) (*BoltJobStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("job store path is required")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	// A timeout avoids blocking forever if another process holds the file lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+
+	return &BoltJobStore{db: db}, nil
+}
+
+// Save writes the job to the store, replacing any existing record
+func (bs *BoltJobStore) Save(job *models.ConversionJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.JobID), data)
+	})
+}
+
+// Delete removes the job from the store
+func (bs *BoltJobStore) Delete(jobID string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(jobID))
+	})
+}
+
+// List returns every job in the store
+func (bs *BoltJobStore) List() ([]*models.ConversionJob, error) {
+	var jobs []*models.ConversionJob
+
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(key, value []byte) error {
+			var job models.ConversionJob
+			if err := json.Unmarshal(value, &job); err != nil {
+				return fmt.Errorf("failed to decode job %s: %w", key, err)
+			}
+			jobs = append(jobs, &job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Close closes the underlying database file
+func (bs *BoltJobStore) Close() error {
+	return bs.db.Close()
+}
+
+// MemoryJobStore implements JobStore in memory. Jobs do not survive restarts;
+// it is intended for tests and deployments that opt out of persistence.
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*models.ConversionJob
+}
+
+// NewMemoryJobStore creates an empty in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{
+		jobs: make(map[string]*models.ConversionJob),
+	}
+}
+
+// Save stores a copy of the job
+func (ms *MemoryJobStore) Save(job *models.ConversionJob) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.jobs[job.JobID] = cloneJob(job)
+	return nil
+}
+
+// Delete removes the job from the store
+func (ms *MemoryJobStore) Delete(jobID string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.jobs, jobID)
+	return nil
+}
+
+// List returns copies of every stored job
+func (ms *MemoryJobStore) List() ([]*models.ConversionJob, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	jobs := make([]*models.ConversionJob, 0, len(ms.jobs))
+	for _, job := range ms.jobs {
+		jobs = append(jobs, cloneJob(job))
+	}
+	return jobs, nil
+}
+
+// Close is a no-op for the in-memory store
+func (ms *MemoryJobStore) Close() error {
+	return nil
+}
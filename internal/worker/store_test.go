@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestBoltJobStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+
+	store, err := NewBoltJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open job store: %v", err)
+	}
+
+	job := &models.ConversionJob{
+		JobID:     "job-1",
+		VideoID:   "video-1",
+		Template:  "default",
+		Source:    models.SourceConfig{URI: "/videos/in.mp4", Type: "local"},
+		Metadata:  map[string]string{"tenant": "acme"},
+		CreatedAt: time.Now(),
+		Status:    models.JobStatus{State: models.JobStateProcessing},
+	}
+	if err := store.Save(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+	if err := store.Save(&models.ConversionJob{JobID: "job-2"}); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+	if err := store.Delete("job-2"); err != nil {
+		t.Fatalf("Failed to delete job: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close job store: %v", err)
+	}
+
+	// Reopen to verify the job survived
+	store, err = NewBoltJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen job store: %v", err)
+	}
+	defer store.Close()
+
+	jobs, err := store.List()
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Expected 1 stored job, got %d", len(jobs))
+	}
+
+	got := jobs[0]
+	if got.JobID != "job-1" || got.Source.URI != job.Source.URI || got.Metadata["tenant"] != "acme" {
+		t.Errorf("Stored job does not match: %+v", got)
+	}
+	if got.Status.State != models.JobStateProcessing {
+		t.Errorf("Expected state processing, got %s", got.Status.State)
+	}
+}
+
+func TestRecoverJobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	created := time.Now().Add(-time.Hour)
+	nextRetry := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	history := []models.JobAttempt{{Attempt: 1, Error: "connection reset", Retryable: true}}
+
+	// The job store as a stopped service left it
+	store, err := NewBoltJobStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open job store: %v", err)
+	}
+	for _, job := range []*models.ConversionJob{
+		{JobID: "interrupted", CreatedAt: created, Attempts: 2, History: history,
+			Status: models.JobStatus{State: models.JobStateProcessing, Progress: 0.4}},
+		{JobID: "waiting-retry", CreatedAt: created.Add(time.Second), Attempts: 1, History: history,
+			Status: models.JobStatus{State: models.JobStatePending, NextRetryAt: nextRetry}},
+		{JobID: "queued", CreatedAt: created.Add(2 * time.Second),
+			Status: models.JobStatus{State: models.JobStatePending}},
+		{JobID: "completed", CreatedAt: created,
+			Status: models.JobStatus{State: models.JobStateCompleted}},
+	} {
+		if err := store.Save(job); err != nil {
+			t.Fatalf("Failed to save job: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close job store: %v", err)
+	}
+
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			TempDir:           dir,
+			JobStore:          config.JobStoreConfig{Type: "bolt", Path: path},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: dir},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: dir}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+	defer func() {
+		w.cancel()
+		w.wg.Wait()
+		w.store.Close()
+	}()
+
+	w.wg.Add(1)
+	w.recoverJobs()
+
+	// The interrupted job is pending again with its attempts kept
+	interrupted, err := w.GetJob("interrupted")
+	if err != nil {
+		t.Fatalf("Expected interrupted job to be recovered: %v", err)
+	}
+	if interrupted.Status.State != models.JobStatePending || interrupted.Status.Progress != 0 {
+		t.Errorf("Expected interrupted job to be pending from the start, got %+v", interrupted.Status)
+	}
+	if interrupted.Attempts != 2 || len(interrupted.History) != 1 {
+		t.Errorf("Expected attempts and history to be kept, got %d attempts, %v", interrupted.Attempts, interrupted.History)
+	}
+
+	// The job waiting out a backoff keeps its schedule instead of being queued
+	waiting, err := w.GetJob("waiting-retry")
+	if err != nil {
+		t.Fatalf("Expected waiting job to be recovered: %v", err)
+	}
+	if waiting.Status.State != models.JobStatePending || !waiting.Status.NextRetryAt.Equal(nextRetry) || waiting.Attempts != 1 {
+		t.Errorf("Expected retry at %v to be kept, got %+v", nextRetry, waiting.Status)
+	}
+
+	positions := w.scheduler.positions()
+	if len(positions) != 2 || positions["interrupted"] != 1 || positions["queued"] != 2 {
+		t.Errorf("Expected the interrupted and queued jobs in creation order, got %v", positions)
+	}
+
+	// Finished jobs are dropped and recovered states persisted
+	if _, err := w.GetJob("completed"); err == nil {
+		t.Error("Expected the completed job not to be recovered")
+	}
+	stored, err := w.store.List()
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	states := make(map[string]models.JobState)
+	for _, job := range stored {
+		states[job.JobID] = job.Status.State
+	}
+	if len(states) != 3 || states["interrupted"] != models.JobStatePending {
+		t.Errorf("Expected 3 pending jobs in the store, got %v", states)
+	}
+}
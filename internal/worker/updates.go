@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+// updateHub fans job updates out to subscribers. Publishing never blocks:
+// each subscriber queues at most one pending progress update per job, which
+// newer updates for the job replace. When the queue is full the oldest
+// progress update is evicted. Terminal updates are never dropped, so a slow
+// subscriber still learns how every job ended.
+type updateHub struct {
+	mu          sync.Mutex
+	subscribers map[*updateSubscriber]struct{}
+}
+
+// updateSubscriber queues updates for one subscriber and delivers them on
+// its channel from its own goroutine
+type updateSubscriber struct {
+	ch    chan models.JobUpdate
+	limit int
+	ready chan struct{}
+	done  chan struct{}
+
+	mu      sync.Mutex
+	pending []models.JobUpdate
+}
+
+// newUpdateHub creates a hub with no subscribers
+func newUpdateHub() *updateHub {
+	return &updateHub{
+		subscribers: make(map[*updateSubscriber]struct{}),
+	}
+}
+
+// subscribe registers a new subscriber that queues up to limit progress
+// updates
+func (h *updateHub) subscribe(limit int) *updateSubscriber {
+	sub := &updateSubscriber{
+		ch:    make(chan models.JobUpdate),
+		limit: max(limit, 1),
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go sub.deliver()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes a subscriber. Its channel is closed once its
+// delivery goroutine stops; queued updates are discarded.
+func (h *updateHub) unsubscribe(sub *updateSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.done)
+	}
+}
+
+// publish queues an update for every subscriber
+func (h *updateHub) publish(update models.JobUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		sub.enqueue(update)
+	}
+}
+
+// enqueue adds an update to the queue, replacing the job's pending progress
+// update if it has one
+func (s *updateSubscriber) enqueue(update models.JobUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := false
+	for i, pending := range s.pending {
+		if pending.JobID == update.JobID && !pending.Status.State.IsTerminal() {
+			s.pending[i] = update
+			queued = true
+			break
+		}
+	}
+
+	if !queued {
+		if len(s.pending) >= s.limit && !s.evictProgress() && !update.Status.State.IsTerminal() {
+			// The queue holds only terminal updates
+			return
+		}
+		s.pending = append(s.pending, update)
+	}
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// evictProgress removes the oldest queued progress update and reports
+// whether there was one
+func (s *updateSubscriber) evictProgress() bool {
+	for i, pending := range s.pending {
+		if !pending.Status.State.IsTerminal() {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// next removes and returns the oldest queued update
+func (s *updateSubscriber) next() (models.JobUpdate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		return models.JobUpdate{}, false
+	}
+	update := s.pending[0]
+	s.pending = s.pending[1:]
+	return update, true
+}
+
+// deliver sends queued updates on the subscriber's channel until the
+// subscription is cancelled, then closes the channel
+func (s *updateSubscriber) deliver() {
+	defer close(s.ch)
+
+	for {
+		update, ok := s.next()
+		if !ok {
+			select {
+			case <-s.ready:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case s.ch <- update:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Subscribe returns a channel of job status updates and a function that
+// cancels the subscription. A subscriber that falls more than buffer jobs
+// behind loses the oldest progress updates, but every job's terminal
+// update is delivered.
+func (w *Worker) Subscribe(buffer int) (<-chan models.JobUpdate, func()) {
+	sub := w.updates.subscribe(buffer)
+	return sub.ch, func() { w.updates.unsubscribe(sub) }
+}
+
+// publishUpdate notifies subscribers of a job's current status
+func (w *Worker) publishUpdate(job *models.ConversionJob) {
+	w.updates.publish(models.JobUpdate{
+		JobID:         job.JobID,
+		CorrelationID: job.CorrelationID,
+		VideoID:       job.VideoID,
+		Status:        job.Status,
+		Timestamp:     time.Now(),
+	})
+}
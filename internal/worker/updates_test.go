@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestUpdateSubscriber_CoalescesAndKeepsTerminalUpdates(t *testing.T) {
+	// Without the delivery goroutine the queue can be inspected directly
+	sub := &updateSubscriber{limit: 2, ready: make(chan struct{}, 1)}
+	progress := func(jobID string, progress float64) models.JobUpdate {
+		return models.JobUpdate{JobID: jobID, Status: models.JobStatus{State: models.JobStateProcessing, Progress: progress}}
+	}
+	finished := func(jobID string) models.JobUpdate {
+		return models.JobUpdate{JobID: jobID, Status: models.JobStatus{State: models.JobStateCompleted, Progress: 1}}
+	}
+
+	sub.enqueue(progress("job-1", 0.1))
+	sub.enqueue(progress("job-2", 0.1))
+	sub.enqueue(progress("job-1", 0.5)) // replaces job-1's pending update
+	sub.enqueue(progress("job-3", 0.1)) // queue full, evicts job-1
+	sub.enqueue(finished("job-2"))      // replaces job-2's pending update
+	sub.enqueue(finished("job-1"))      // queue full, evicts job-3
+	sub.enqueue(progress("job-4", 0.1)) // only terminal updates queued, dropped
+	sub.enqueue(finished("job-3"))      // terminal updates are never dropped
+
+	var got []string
+	for {
+		update, ok := sub.next()
+		if !ok {
+			break
+		}
+		got = append(got, fmt.Sprintf("%s:%s:%.1f", update.JobID, update.Status.State, update.Status.Progress))
+	}
+	expected := "job-2:completed:1.0 job-1:completed:1.0 job-3:completed:1.0"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+	}
+}
+
+func TestUpdateHub_DeliversAndClosesOnUnsubscribe(t *testing.T) {
+	hub := newUpdateHub()
+	sub := hub.subscribe(1)
+
+	hub.publish(models.JobUpdate{JobID: "job-1"})
+	select {
+	case update := <-sub.ch:
+		if update.JobID != "job-1" {
+			t.Errorf("Expected job-1, got %s", update.JobID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update for job-1")
+	}
+
+	hub.unsubscribe(sub)
+	hub.unsubscribe(sub) // second call is a no-op
+	select {
+	case _, ok := <-sub.ch:
+		if ok {
+			t.Error("Expected channel to be closed after unsubscribe")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected channel to be closed after unsubscribe")
+	}
+
+	// Publishing with no subscribers must not block
+	hub.publish(models.JobUpdate{JobID: "job-3"})
+}
@@ -2,11 +2,19 @@ package worker
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/matt-primrose/video-converter-service/internal/config"
@@ -15,12 +23,46 @@ import (
 	"github.com/matt-primrose/video-converter-service/pkg/models"
 )
 
+var (
+	// ErrJobNotFound is returned when a job ID is not known to the worker
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobExists is returned when a job is submitted with an ID that is already in use
+	ErrJobExists = errors.New("job already exists")
+
+	// ErrQueueFull is returned when the job backlog cannot accept more jobs;
+	// callers should ask clients to retry after QueueFullRetryAfter
+	ErrQueueFull = errors.New("job queue is full")
+
+	// ErrJobNotCancellable is returned when a job is no longer in a cancellable state
+	ErrJobNotCancellable = errors.New("job cannot be cancelled in its current state")
+
+	// ErrInvalidJob is returned when a job fails validation
+	ErrInvalidJob = errors.New("invalid job")
+)
+
+// QueueFullRetryAfter is how long clients are asked to wait before
+// resubmitting a job refused with ErrQueueFull
+const QueueFullRetryAfter = 30 * time.Second
+
 // Worker manages the conversion job processing
 type Worker struct {
 	config        *config.Config
 	transcoder    *transcoder.Transcoder
 	outputStorage storage.Storage
-	jobQueue      chan *models.ConversionJob
+	scheduler     *jobScheduler
+	registry      *jobRegistry
+	store         JobStore
+	deadLetters   DeadLetterStore
+	dedup         DedupStore
+	httpClient    *http.Client
+	updates       *updateHub
+	running       map[string]*runningJob
+	runningMu     sync.Mutex
+	draining      atomic.Bool
+	intakeCtx     context.Context // Cancelled when draining so workers stop taking jobs
+	stopIntake    context.CancelFunc
+	workers       sync.WaitGroup // Worker loops only
 	wg            sync.WaitGroup
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -44,13 +86,49 @@ func New(cfg *config.Config) (*Worker, error) {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
+	// Initialize dead-letter store for permanently failed jobs
+	deadLetters, err := NewDeadLetterStore(cfg, outputStorage)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize dead letter store: %w", err)
+	}
+
+	// Initialize durable job store
+	store, err := NewJobStore(cfg)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+
+	// Initialize deduplication store for idempotent intake
+	dedup, err := NewDedupStore(cfg)
+	if err != nil {
+		store.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to initialize dedup store: %w", err)
+	}
+
+	intakeCtx, stopIntake := context.WithCancel(ctx)
+
 	return &Worker{
 		config:        cfg,
 		transcoder:    tc,
 		outputStorage: outputStorage,
-		jobQueue:      make(chan *models.ConversionJob, cfg.Processing.MaxConcurrentJobs*2), // Buffer for queuing
-		ctx:           ctx,
-		cancel:        cancel,
+		scheduler:     newJobScheduler(cfg.Processing.MaxQueuedJobs),
+		registry: newJobRegistry(
+			time.Duration(cfg.Processing.JobRetentionMinutes)*time.Minute,
+			cfg.Processing.MaxRetainedJobs,
+		),
+		store:       store,
+		deadLetters: deadLetters,
+		dedup:       dedup,
+		httpClient:  &http.Client{},
+		updates:     newUpdateHub(),
+		running:     make(map[string]*runningJob),
+		intakeCtx:   intakeCtx,
+		stopIntake:  stopIntake,
+		ctx:         ctx,
+		cancel:      cancel,
 	}, nil
 }
 
@@ -62,9 +140,17 @@ func (w *Worker) Start(ctx context.Context) {
 	// Start worker goroutines
 	for i := 0; i < w.config.Processing.MaxConcurrentJobs; i++ {
 		w.wg.Add(1)
+		w.workers.Add(1)
 		go w.workerLoop(i)
 	}
 
+	// Replay jobs left over from a previous run
+	w.wg.Add(1)
+	go w.recoverJobs()
+
+	w.wg.Add(1)
+	go w.pruneDedupKeys()
+
 	// Wait for context cancellation
 	<-ctx.Done()
 	slog.Info("Stopping worker pool...")
@@ -72,50 +158,310 @@ func (w *Worker) Start(ctx context.Context) {
 	// Cancel all workers
 	w.cancel()
 
-	// Close job queue to prevent new jobs
-	close(w.jobQueue)
-
 	// Wait for all workers to finish
 	w.wg.Wait()
+
+	if err := w.store.Close(); err != nil {
+		slog.Error("Failed to close job store", "error", err)
+	}
+	if err := w.dedup.Close(); err != nil {
+		slog.Error("Failed to close dedup store", "error", err)
+	}
 	slog.Info("Worker pool stopped")
 }
 
-// SubmitJob submits a new job to the worker queue
+// recoverJobs re-enqueues jobs found in the job store at startup.
+// Jobs that were mid-processing when the service stopped are reset to pending
+// so they are retried from the beginning.
+func (w *Worker) recoverJobs() {
+	defer w.wg.Done()
+
+	jobs, err := w.store.List()
+	if err != nil {
+		slog.Error("Failed to load jobs from job store", "error", err)
+		return
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	recovered := 0
+	for _, job := range jobs {
+		if job.Status.State.IsTerminal() {
+			if err := w.store.Delete(job.JobID); err != nil {
+				slog.Warn("Failed to delete finished job from job store", "jobId", job.JobID, "error", err)
+			}
+			continue
+		}
+
+		if job.Status.State == models.JobStateProcessing {
+			slog.Warn("Job was interrupted while processing, marking for retry", "jobId", job.JobID)
+			job.Status = models.JobStatus{
+				State:   models.JobStatePending,
+				Message: "Recovered after restart; previous attempt was interrupted",
+			}
+		}
+
+		if err := w.registry.Add(job); err != nil {
+			slog.Warn("Skipping recovered job", "jobId", job.JobID, "error", err)
+			continue
+		}
+		w.persistJob(job)
+
+		// Jobs waiting out a retry backoff keep their schedule
+		if delay := time.Until(job.Status.NextRetryAt); delay > 0 {
+			w.scheduleRetry(job.JobID, delay)
+			recovered++
+			continue
+		}
+
+		w.scheduler.push(job, w.jobTenant(job), true)
+		recovered++
+	}
+
+	if recovered > 0 {
+		slog.Info("Recovered jobs from job store", "count", recovered)
+	}
+}
+
+// validSourceTypes lists the source types the worker knows how to download
+var validSourceTypes = []string{"local", "http", "https", "azure-blob", "s3"}
+
+// ValidateJob checks a job received from a client and fills in defaults
+func (w *Worker) ValidateJob(job *models.ConversionJob) error {
+	if job.Source.URI == "" {
+		return fmt.Errorf("source.uri is required")
+	}
+
+	job.Source.Type = strings.ToLower(job.Source.Type)
+	if job.Source.Type == "" {
+		return fmt.Errorf("source.type is required")
+	}
+	if !slices.Contains(validSourceTypes, job.Source.Type) {
+		return fmt.Errorf("invalid source.type: %s", job.Source.Type)
+	}
+
+	for i := range job.Captions {
+		caption := &job.Captions[i]
+		if caption.URI == "" {
+			return fmt.Errorf("captions[%d].uri is required", i)
+		}
+		caption.Type = strings.ToLower(caption.Type)
+		if caption.Type == "" {
+			caption.Type = job.Source.Type
+		}
+		if !slices.Contains(validSourceTypes, caption.Type) {
+			return fmt.Errorf("invalid captions[%d].type: %s", i, caption.Type)
+		}
+	}
+
+	if job.VideoID == "" {
+		return fmt.Errorf("videoId is required")
+	}
+
+	if job.Template == "" {
+		job.Template = "default"
+	}
+	if _, exists := w.config.JobTemplates[job.Template]; !exists {
+		return fmt.Errorf("unknown job template: %s", job.Template)
+	}
+
+	// Clients request a priority through metadata, within the configured
+	// maximum; the resolved priority is set on submit
+	job.Priority = 0
+	if value, ok := job.Metadata[priorityMetadataKey]; ok {
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("metadata.%s must be an integer: %s", priorityMetadataKey, value)
+		}
+		if maxPriority := w.config.Processing.MaxClientPriority; priority > maxPriority {
+			return fmt.Errorf("metadata.%s must not be above %d: %d", priorityMetadataKey, maxPriority, priority)
+		}
+	}
+
+	return nil
+}
+
+// SubmitJob submits a new job to the worker queue.
+// A job ID is generated if the job does not carry one, and the job's
+// priority is resolved from its metadata or template unless already set.
 func (w *Worker) SubmitJob(job *models.ConversionJob) error {
+	if w.Draining() {
+		return ErrDraining
+	}
+	if job.JobID == "" {
+		job.JobID = GenerateJobID()
+	}
+	if job.Priority == 0 {
+		job.Priority = jobPriority(job, w.config.JobTemplates[job.Template].Priority)
+	}
 	job.CreatedAt = time.Now()
 	job.Status = models.JobStatus{
 		State:   models.JobStatePending,
 		Message: "Job queued for processing",
 	}
 
-	select {
-	case w.jobQueue <- job:
-		slog.Info("Job queued", "jobId", job.JobID)
+	if err := w.registry.Add(job); err != nil {
+		return err
+	}
+
+	// Record the submission durably before acknowledging it
+	if err := w.store.Save(job); err != nil {
+		w.registry.Remove(job.JobID)
+		return fmt.Errorf("failed to persist job: %w", err)
+	}
+
+	tenant := w.jobTenant(job)
+	if err := w.scheduler.push(job, tenant, false); err != nil {
+		w.registry.Remove(job.JobID)
+		if err := w.store.Delete(job.JobID); err != nil {
+			slog.Warn("Failed to remove rejected job from job store", "jobId", job.JobID, "error", err)
+		}
+		slog.Warn("Job queue is full, rejecting job", "jobId", job.JobID, "tenant", tenant)
+		return err
+	}
+
+	// The caller's copy reports where the job landed; the registry's copy
+	// gets positions computed on read
+	w.withQueuePositions(job)
+
+	slog.Info("Job queued",
+		"jobId", job.JobID,
+		"tenant", tenant,
+		"priority", job.Priority,
+		"queuePosition", job.Status.QueuePosition,
+	)
+	return nil
+}
+
+// GetJob returns a snapshot of the job with the given ID
+func (w *Worker) GetJob(jobID string) (*models.ConversionJob, error) {
+	job, err := w.registry.Get(jobID)
+	if err != nil {
+		return nil, err
+	}
+	w.withQueuePositions(job)
+	return job, nil
+}
+
+// ListJobs returns snapshots of all known jobs ordered by creation time.
+// If states are given, only jobs in one of those states are returned.
+func (w *Worker) ListJobs(states ...models.JobState) []*models.ConversionJob {
+	jobs := w.registry.List(states...)
+	w.withQueuePositions(jobs...)
+	return jobs
+}
+
+// QueueLength returns the number of jobs waiting for a worker
+func (w *Worker) QueueLength() int {
+	return w.scheduler.len()
+}
+
+// CancelJob cancels a queued or running job. A queued job is cancelled
+// immediately. A running job has its context cancelled, which stops ffmpeg
+// and any transfer in progress; it stays processing until its temp files and
+// partial outputs are removed, then moves to cancelled.
+func (w *Worker) CancelJob(jobID string) (*models.ConversionJob, error) {
+	var stateErr error
+	var run *runningJob
+	job, err := w.registry.Update(jobID, func(job *models.ConversionJob) {
+		switch job.Status.State {
+		case models.JobStatePending:
+			w.scheduler.remove(jobID)
+			job.Status.State = models.JobStateCancelled
+			job.Status.Message = "Job cancelled before processing"
+			job.Status.CompletedAt = time.Now()
+		case models.JobStateProcessing:
+			if run = w.runningJob(jobID); run == nil {
+				stateErr = fmt.Errorf("%w: job is finishing", ErrJobNotCancellable)
+				return
+			}
+			job.Status.Message = "Cancelling"
+		default:
+			stateErr = fmt.Errorf("%w: job is %s", ErrJobNotCancellable, job.Status.State)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stateErr != nil {
+		return nil, stateErr
+	}
+
+	if run != nil {
+		run.cancel(errJobCancelled)
+		w.publishUpdate(job)
+		slog.Info("Cancelling running job", "jobId", jobID)
+		return job, nil
+	}
+
+	w.persistJob(job)
+	w.publishUpdate(job)
+
+	slog.Info("Job cancelled", "jobId", jobID)
+	return job, nil
+}
+
+// updateJob applies a mutation to the registry's record of a job
+func (w *Worker) updateJob(jobID string, update func(job *models.ConversionJob)) {
+	job, err := w.registry.Update(jobID, update)
+	if err != nil {
+		slog.Warn("Failed to update job record", "jobId", jobID, "error", err)
+		return
+	}
+	w.publishUpdate(job)
+}
+
+// transitionJob applies a state change to a job, records it in the job store
+// and returns the updated snapshot (nil if the job is no longer known)
+func (w *Worker) transitionJob(jobID string, update func(job *models.ConversionJob)) *models.ConversionJob {
+	job, err := w.registry.Update(jobID, update)
+	if err != nil {
+		slog.Warn("Failed to update job record", "jobId", jobID, "error", err)
 		return nil
-	default:
-		return fmt.Errorf("job queue is full")
+	}
+	w.persistJob(job)
+	w.publishUpdate(job)
+	return job
+}
+
+// persistJob writes the job to the job store. Finished jobs are removed
+// since they no longer need to be recovered after a restart; their outcome
+// is kept with any dedup keys they hold.
+func (w *Worker) persistJob(job *models.ConversionJob) {
+	var err error
+	if job.Status.State.IsTerminal() {
+		w.recordDedupJob(job)
+		err = w.store.Delete(job.JobID)
+	} else {
+		err = w.store.Save(job)
+	}
+	if err != nil {
+		slog.Error("Failed to persist job", "jobId", job.JobID, "state", job.Status.State, "error", err)
 	}
 }
 
 // workerLoop is the main processing loop for a single worker
 func (w *Worker) workerLoop(workerID int) {
 	defer w.wg.Done()
+	defer w.workers.Done()
 
 	slog.Info("Starting worker", "workerId", workerID)
 
 	for {
-		select {
-		case <-w.ctx.Done():
+		entry, err := w.scheduler.next(w.intakeCtx)
+		if err != nil {
 			slog.Info("Worker stopping", "workerId", workerID)
 			return
-		case job, ok := <-w.jobQueue:
-			if !ok {
-				slog.Info("Job queue closed, worker stopping", "workerId", workerID)
-				return
-			}
+		}
 
-			w.processJob(workerID, job)
+		if state, _ := w.registry.State(entry.job.JobID); state == models.JobStateCancelled {
+			slog.Info("Skipping cancelled job", "workerId", workerID, "jobId", entry.job.JobID)
+		} else {
+			w.processJob(workerID, entry.job)
 		}
+		w.scheduler.done(entry)
 	}
 }
 
@@ -128,76 +474,205 @@ func (w *Worker) processJob(workerID int, job *models.ConversionJob) {
 		"template", job.Template,
 	)
 
-	// Update job status
-	job.Status.State = models.JobStateProcessing
-	job.Status.StartedAt = time.Now()
-	job.Status.Message = "Processing started"
+	// Register a cancel handle before the job is visible as processing
+	runCtx, cancelRun := context.WithCancelCause(w.ctx)
+	defer cancelRun(nil)
+	run := w.trackJob(job.JobID, cancelRun)
+	defer w.untrackJob(job.JobID)
+
+	// Update job status, unless it was cancelled while waiting in the queue
+	startedAt := time.Now()
+	attempt := job.Attempts + 1
+	cancelled := false
+	w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+		if job.Status.State == models.JobStateCancelled {
+			cancelled = true
+			return
+		}
+		job.Attempts = attempt
+		job.Status.State = models.JobStateProcessing
+		job.Status.StartedAt = startedAt
+		job.Status.NextRetryAt = time.Time{}
+		job.Status.Progress = 0
+		job.Status.Message = "Processing started"
+	})
+	if cancelled {
+		slog.Info("Skipping cancelled job", "workerId", workerID, "jobId", job.JobID)
+		return
+	}
 
 	// Get job template
 	template, exists := w.config.JobTemplates[job.Template]
 	if !exists {
-		job.Status.State = models.JobStateFailed
-		job.Status.Error = fmt.Sprintf("Job template '%s' not found", job.Template)
-		job.Status.CompletedAt = time.Now()
+		err := fmt.Errorf("job template '%s' not found", job.Template)
+		failed := w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+			job.Status.State = models.JobStateFailed
+			job.Status.Message = "Job template not found"
+			job.Status.Error = err.Error()
+			job.Status.CompletedAt = time.Now()
+		})
 		slog.Error("Job template not found",
 			"jobId", job.JobID,
 			"template", job.Template,
 		)
+		if failed != nil {
+			w.deadLetter(failed, err)
+			w.notifyMissingTemplate(failed)
+		}
 		return
 	}
 
 	// Process the job with timeout
-	jobCtx, cancel := context.WithTimeout(w.ctx,
+	jobCtx, cancel := context.WithTimeout(runCtx,
 		time.Duration(w.config.Processing.JobTimeoutMinutes)*time.Minute)
 	defer cancel()
 
-	if err := w.executeConversion(jobCtx, job, &template); err != nil {
-		job.Status.State = models.JobStateFailed
-		job.Status.Error = err.Error()
-		job.Status.CompletedAt = time.Now()
+	result, err := w.executeConversion(jobCtx, job, &template, run)
+
+	// From here on the outcome is decided; a cancel request either already
+	// arrived or is refused
+	w.untrackJob(job.JobID)
+	if errors.Is(context.Cause(runCtx), errJobCancelled) {
+		w.finishCancelledJob(job, run)
+		return
+	}
+
+	if err != nil && w.ctx.Err() != nil {
+		// The worker is shutting down; leave the job in the store as
+		// processing so it is retried on the next start
+		w.updateJob(job.JobID, func(job *models.ConversionJob) {
+			job.Status.Message = "Interrupted by shutdown; will be retried on restart"
+		})
+		slog.Warn("Job interrupted by shutdown",
+			"jobId", job.JobID,
+			"error", err,
+		)
+		return
+	}
+	if err != nil {
+		// A job that ran out of time would most likely time out again
+		retryable := jobCtx.Err() == nil && isRetryable(err)
+		policy := newRetryPolicy(template.Retry)
+		record := models.JobAttempt{
+			Attempt:    attempt,
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+			Error:      err.Error(),
+			Retryable:  retryable,
+		}
+
+		if retryable && attempt < policy.maxAttempts {
+			delay := policy.backoff(attempt)
+			w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+				job.History = append(job.History, record)
+				job.Status.State = models.JobStatePending
+				job.Status.Message = fmt.Sprintf("Attempt %d of %d failed; retrying in %s",
+					attempt, policy.maxAttempts, formatDuration(delay))
+				job.Status.Error = err.Error()
+				job.Status.NextRetryAt = time.Now().Add(delay)
+			})
+			slog.Warn("Job attempt failed, will retry",
+				"jobId", job.JobID,
+				"attempt", attempt,
+				"maxAttempts", policy.maxAttempts,
+				"retryIn", delay,
+				"error", err,
+			)
+			w.scheduleRetry(job.JobID, delay)
+			return
+		}
+
+		message := "Conversion failed"
+		if attempt > 1 {
+			message = fmt.Sprintf("Conversion failed after %d attempts", attempt)
+		}
+		failed := w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+			job.History = append(job.History, record)
+			job.Status.State = models.JobStateFailed
+			job.Status.Message = message
+			job.Status.Error = err.Error()
+			job.Status.CompletedAt = time.Now()
+		})
 		slog.Error("Job conversion failed",
 			"jobId", job.JobID,
+			"attempt", attempt,
+			"retryable", retryable,
 			"error", err,
 		)
+		if failed != nil {
+			w.deadLetter(failed, err)
+			w.notifyJob(failed, &template)
+		}
 		return
 	}
 
 	// Mark job as completed
-	job.Status.State = models.JobStateCompleted
-	job.Status.Progress = 1.0
-	job.Status.CompletedAt = time.Now()
-	job.Status.Message = "Conversion completed successfully"
+	completedAt := time.Now()
+	completed := w.transitionJob(job.JobID, func(job *models.ConversionJob) {
+		job.Status.State = models.JobStateCompleted
+		job.Status.Progress = 1.0
+		job.Status.CompletedAt = completedAt
+		job.Status.Message = "Conversion completed successfully"
+		job.Status.Error = ""
+		job.Result = result
+	})
+	if completed != nil {
+		w.notifyJob(completed, &template)
+	}
 
 	slog.Info("Job completed",
 		"workerId", workerID,
 		"jobId", job.JobID,
-		"completed_at", job.Status.CompletedAt.Format(time.RFC3339),
+		"completed_at", completedAt.Format(time.RFC3339),
 	)
 }
 
-// executeConversion performs the actual video conversion
-func (w *Worker) executeConversion(ctx context.Context, job *models.ConversionJob, template *config.JobTemplate) error {
+// executeConversion performs the actual video conversion and returns its result
+func (w *Worker) executeConversion(ctx context.Context, job *models.ConversionJob,
+	template *config.JobTemplate, run *runningJob) (*models.ConversionResult, error) {
 	slog.Info("Starting conversion execution",
 		"jobId", job.JobID,
 		"sourceUri", job.Source.URI,
 		"outputCount", len(template.Outputs),
 	)
 
+	startTime := time.Now()
+
+	// Remove the job temp directory however the attempt ends, before a
+	// retry can be scheduled into it
+	jobTempDir := filepath.Join(w.config.Processing.TempDir, job.JobID)
+	defer func() {
+		if err := os.RemoveAll(jobTempDir); err != nil {
+			slog.Warn("Failed to clean up job temp directory", "jobId", job.JobID, "path", jobTempDir, "error", err)
+		}
+	}()
+
 	// Step 1: Download source file from job.Source.URI
+	w.updateJob(job.JobID, func(job *models.ConversionJob) {
+		job.Status.Message = "Downloading source file"
+	})
 	inputPath, err := w.downloadSourceFile(ctx, job)
 	if err != nil {
-		return fmt.Errorf("failed to download source file: %w", err)
+		return nil, fmt.Errorf("failed to download source file: %w", err)
 	}
-	// Note: File cleanup is handled after upload by cleaning the entire job temp directory
+	captions, err := w.downloadCaptionFiles(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	downloadTime := time.Since(startTime)
 
 	// Step 2: Validate source file (basic validation)
-	if err := w.validateSourceFile(inputPath); err != nil {
-		return fmt.Errorf("source file validation failed: %w", err)
+	sourceSize, err := w.validateSourceFile(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("source file validation failed: %w", err)
 	}
 
 	// Step 3: Progress callback to update job status
 	progressCallback := func(progress float64, currentFrame, totalFrames int, speed float64) {
-		job.Status.Progress = progress
+		w.updateJob(job.JobID, func(job *models.ConversionJob) {
+			job.Status.Progress = progress
+			job.Status.Speed = speed
+		})
 		slog.Debug("Conversion progress",
 			"jobId", job.JobID,
 			"progress", fmt.Sprintf("%.2f%%", progress*100),
@@ -208,27 +683,23 @@ func (w *Worker) executeConversion(ctx context.Context, job *models.ConversionJo
 	}
 
 	// Step 4: Perform transcoding
-	result, err := w.transcoder.Transcode(ctx, job, template, inputPath, progressCallback)
+	w.updateJob(job.JobID, func(job *models.ConversionJob) {
+		job.Status.Message = "Transcoding"
+	})
+	result, err := w.transcoder.Transcode(ctx, job, template, inputPath, captions, progressCallback)
 	if err != nil {
-		return fmt.Errorf("transcoding failed: %w", err)
+		return nil, fmt.Errorf("transcoding failed: %w", err)
 	}
 
-	// Step 5: Upload output files to storage (placeholder)
-	if err := w.uploadOutputFiles(ctx, job, result); err != nil {
-		return fmt.Errorf("failed to upload output files: %w", err)
-	}
-
-	// Step 5.5: Cleanup job temp directory after successful upload
-	jobTempDir := filepath.Join(w.config.Processing.TempDir, job.JobID)
-	if err := os.RemoveAll(jobTempDir); err != nil {
-		slog.Warn("Failed to clean up job temp directory", "jobId", job.JobID, "path", jobTempDir, "error", err)
-	}
-
-	// Step 6: Send notifications if configured
-	if err := w.sendNotifications(ctx, job, template, result); err != nil {
-		slog.Warn("Failed to send notifications", "jobId", job.JobID, "error", err)
-		// Don't fail the job for notification errors
+	// Step 5: Upload output files to storage
+	w.updateJob(job.JobID, func(job *models.ConversionJob) {
+		job.Status.Message = "Uploading output files"
+	})
+	uploadStart := time.Now()
+	if err := w.uploadOutputFiles(ctx, job, template, result, run); err != nil {
+		return nil, fmt.Errorf("failed to upload output files: %w", err)
 	}
+	uploadTime := time.Since(uploadStart)
 
 	slog.Info("Conversion execution completed",
 		"jobId", job.JobID,
@@ -236,14 +707,26 @@ func (w *Worker) executeConversion(ctx context.Context, job *models.ConversionJo
 		"outputCount", len(result.Outputs),
 	)
 
-	return nil
+	return buildConversionResult(job, result, sourceSize, time.Since(startTime), downloadTime, uploadTime), nil
 }
 
-// GetJobStatus returns the current status of all jobs (placeholder)
+// GetJobStatus returns the current status of all known jobs keyed by job ID
 func (w *Worker) GetJobStatus() map[string]models.JobStatus {
-	// TODO: Implement job status tracking
-	// This would typically involve storing job statuses in memory or a database
-	return make(map[string]models.JobStatus)
+	return w.registry.Statuses()
+}
+
+// GenerateJobID generates a unique job ID using timestamp and random bytes
+func GenerateJobID() string {
+	// Generate 4 random bytes for uniqueness
+	randomBytes := make([]byte, 4)
+	if _, err := rand.Read(randomBytes); err != nil {
+		// Fallback to timestamp-based ID if random generation fails
+		return fmt.Sprintf("job-%d", time.Now().UnixNano())
+	}
+
+	// Combine timestamp with random bytes for uniqueness
+	timestamp := time.Now().Unix()
+	return fmt.Sprintf("job-%d-%x", timestamp, randomBytes)
 }
 
 // formatDuration formats a time.Duration into a human-readable string
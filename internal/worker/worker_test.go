@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matt-primrose/video-converter-service/internal/config"
+	"github.com/matt-primrose/video-converter-service/pkg/models"
+)
+
+func TestProcessJob_RemovesTempDirOfFailedAttempt(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "temp")
+	source := filepath.Join(dir, "source.mp4")
+	if err := os.WriteFile(source, []byte("not a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// ffprobe always fails, so the attempt fails after the source is downloaded
+	w, err := New(&config.Config{
+		Processing: config.ProcessingConfig{
+			MaxConcurrentJobs: 1,
+			MaxQueuedJobs:     10,
+			JobTimeoutMinutes: 5,
+			TempDir:           tempDir,
+			JobStore:          config.JobStoreConfig{Type: "memory"},
+			DeadLetter:        config.DeadLetterConfig{Type: "disk", Path: filepath.Join(dir, "dead-letters")},
+			Dedup:             config.DedupConfig{Type: "memory", TTLMinutes: 60},
+		},
+		Storage:      config.StorageConfig{Type: "local", Local: config.LocalStorage{Path: filepath.Join(dir, "outputs")}},
+		FFmpeg:       config.FFmpegConfig{BinaryPath: "true", ProbePath: "false"},
+		JobTemplates: config.JobTemplatesConfig{"default": {}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create worker: %v", err)
+	}
+	defer func() {
+		w.cancel()
+		w.wg.Wait()
+	}()
+
+	job := &models.ConversionJob{
+		JobID:    "job-1",
+		Template: "default",
+		Source:   models.SourceConfig{URI: source, Type: "local"},
+		Status:   models.JobStatus{State: models.JobStatePending},
+	}
+	if err := w.registry.Add(job); err != nil {
+		t.Fatalf("Failed to add job: %v", err)
+	}
+
+	w.processJob(0, job)
+
+	if state, _ := w.registry.State(job.JobID); state == models.JobStateProcessing || state == models.JobStateCompleted {
+		t.Fatalf("Expected the attempt to fail, job is %s", state)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, job.JobID)); !os.IsNotExist(err) {
+		t.Errorf("Expected job temp directory to be removed, got %v", err)
+	}
+}
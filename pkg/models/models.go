@@ -9,9 +9,31 @@ type ConversionJob struct {
 	VideoID       string            `json:"videoId"`
 	Template      string            `json:"template"`
 	Source        SourceConfig      `json:"source"`
+	Captions      []CaptionSource   `json:"captions,omitempty"` // Sidecar caption files published with the video
 	Metadata      map[string]string `json:"metadata,omitempty"`
+	Priority      int               `json:"priority,omitempty"` // Higher runs first; resolved from metadata or the template on submit, never taken from clients
 	CreatedAt     time.Time         `json:"createdAt"`
 	Status        JobStatus         `json:"status"`
+	Result        *ConversionResult `json:"result,omitempty"`
+	Attempts      int               `json:"attempts,omitempty"`       // Processing attempts started so far
+	History       []JobAttempt      `json:"attemptHistory,omitempty"` // Failed attempts, oldest first
+}
+
+// DeadLetter records a job that failed permanently, for triage and replay
+type DeadLetter struct {
+	Job      ConversionJob `json:"job"`              // Job as it was when it failed, including attempts and source
+	Error    string        `json:"error"`            // Final error
+	Stderr   string        `json:"stderr,omitempty"` // Tail of ffmpeg/ffprobe stderr if the failure came from one
+	FailedAt time.Time     `json:"failedAt"`
+}
+
+// JobAttempt records a failed processing attempt
+type JobAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Error      string    `json:"error"`
+	Retryable  bool      `json:"retryable"`
 }
 
 // SourceConfig represents the source file configuration
@@ -21,14 +43,27 @@ type SourceConfig struct {
 	Checksum string `json:"checksum,omitempty"`
 }
 
+// CaptionSource is a sidecar caption file (SRT, WebVTT or ASS) attached to a job
+type CaptionSource struct {
+	URI      string `json:"uri"`
+	Type     string `json:"type"`               // http, azure-blob, s3, local
+	Language string `json:"language,omitempty"` // ISO 639-2 language tag
+	Label    string `json:"label,omitempty"`    // Display name, defaults to the language
+	Default  bool   `json:"default,omitempty"`  // Select the captions when the player has no preference
+}
+
 // JobStatus represents the current status of a job
 type JobStatus struct {
-	State       JobState  `json:"state"`
-	Message     string    `json:"message,omitempty"`
-	Progress    float64   `json:"progress"` // 0.0 to 1.0
-	StartedAt   time.Time `json:"startedAt,omitempty"`
-	CompletedAt time.Time `json:"completedAt,omitempty"`
-	Error       string    `json:"error,omitempty"`
+	State         JobState  `json:"state"`
+	Message       string    `json:"message,omitempty"`
+	Progress      float64   `json:"progress"`        // 0.0 to 1.0
+	Speed         float64   `json:"speed,omitempty"` // Encoding speed relative to realtime
+	StartedAt     time.Time `json:"startedAt,omitempty"`
+	CompletedAt   time.Time `json:"completedAt,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt,omitempty"`
+	NextRetryAt   time.Time `json:"nextRetryAt,omitempty"`   // Set while a pending job waits to be retried
+	QueuePosition int       `json:"queuePosition,omitempty"` // Estimated place in line of a queued job, 1 runs next
+	Error         string    `json:"error,omitempty"`
 }
 
 // JobState represents the possible states of a conversion job
@@ -42,6 +77,11 @@ const (
 	JobStateCancelled  JobState = "cancelled"
 )
 
+// IsTerminal reports whether the state is final (completed, failed or cancelled)
+func (s JobState) IsTerminal() bool {
+	return s == JobStateCompleted || s == JobStateFailed || s == JobStateCancelled
+}
+
 // ConversionResult represents the result of a completed conversion
 type ConversionResult struct {
 	JobID      string               `json:"jobId"`
@@ -64,6 +104,8 @@ type ConversionOutput struct {
 // OutputFile represents a single output file
 type OutputFile struct {
 	Path     string `json:"path"`
+	URL      string `json:"url,omitempty"`     // Storage URL for the uploaded file
+	Profile  string `json:"profile,omitempty"` // Rendition the file belongs to, if any
 	Size     int64  `json:"size"`
 	Checksum string `json:"checksum,omitempty"`
 	MimeType string `json:"mimeType,omitempty"`
@@ -80,6 +122,26 @@ type ConversionStatistics struct {
 	ProfilesProcessed int           `json:"profilesProcessed"`
 }
 
+// Notification event types sent to job template webhooks
+const (
+	NotificationJobCompleted = "job.completed"
+	NotificationJobFailed    = "job.failed"
+)
+
+// JobNotification is the webhook payload sent when a job completes or fails
+type JobNotification struct {
+	Event         string            `json:"event"`
+	JobID         string            `json:"jobId"`
+	CorrelationID string            `json:"correlationId,omitempty"`
+	VideoID       string            `json:"videoId"`
+	Template      string            `json:"template"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Status        JobStatus         `json:"status"`
+	Result        *ConversionResult `json:"result,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
 // EventGridEvent represents an Azure Event Grid event
 type EventGridEvent struct {
 	ID          string                 `json:"id"`
@@ -92,8 +154,41 @@ type EventGridEvent struct {
 
 // WebSocketEvent represents an event received via WebSocket
 type WebSocketEvent struct {
-	Type          string        `json:"type"`
-	CorrelationID string        `json:"correlationId,omitempty"`
-	Job           ConversionJob `json:"job,omitempty"`
-	Timestamp     time.Time     `json:"timestamp"`
+	Type           string        `json:"type"`
+	CorrelationID  string        `json:"correlationId,omitempty"`
+	JobID          string        `json:"jobId,omitempty"` // Target of subscribe/unsubscribe; empty means all jobs
+	Job            ConversionJob `json:"job,omitempty"`
+	IdempotencyKey string        `json:"idempotencyKey,omitempty"` // Repeated convert-requests with the same key are acked with the first job
+	Timestamp      time.Time     `json:"timestamp"`
+}
+
+// WebSocket message types
+const (
+	WebSocketEventConvertRequest = "convert-request" // Peer asks for a job to be converted
+	WebSocketEventSubscribe      = "subscribe"       // Peer wants status updates for a job (or all jobs)
+	WebSocketEventUnsubscribe    = "unsubscribe"     // Peer no longer wants status updates
+	WebSocketMessageAck          = "ack"             // Job accepted and queued, or subscription changed
+	WebSocketMessageNack         = "nack"            // Job rejected; see Error
+	WebSocketMessageStatus       = "status"          // Job status changed
+)
+
+// WebSocketMessage is a frame sent to a WebSocket peer in reply to its
+// events. Frames are keyed by the CorrelationID of the originating event.
+type WebSocketMessage struct {
+	Type          string     `json:"type"`
+	CorrelationID string     `json:"correlationId,omitempty"`
+	JobID         string     `json:"jobId,omitempty"`
+	Status        *JobStatus `json:"status,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	RetryAfter    int        `json:"retryAfter,omitempty"` // Seconds to wait before resubmitting a job refused because the queue is full
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
+// JobUpdate describes a change to a job's status
+type JobUpdate struct {
+	JobID         string    `json:"jobId"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	VideoID       string    `json:"videoId"`
+	Status        JobStatus `json:"status"`
+	Timestamp     time.Time `json:"timestamp"`
 }